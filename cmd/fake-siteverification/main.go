@@ -0,0 +1,110 @@
+// Command fake-siteverification serves an in-memory Site Verification API,
+// so that Terraform configurations using the provider can be tested without
+// Google credentials:
+//
+//	fake-siteverification -addr 127.0.0.1:8080 -published after:2
+//
+//	provider "googlesiteverification" {
+//	  endpoint = "http://127.0.0.1:8080/siteVerification/v1/"
+//	  emulator = true
+//	}
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"giautm.dev/googlesiteverification/internal/fake"
+)
+
+func main() {
+	var (
+		addr       string
+		seed       string
+		owner      string
+		published  string
+		removed    string
+		failInsert int
+		failDelete int
+	)
+	flag.StringVar(&addr, "addr", "127.0.0.1:8080", "address to listen on")
+	flag.StringVar(&seed, "seed", "", "seed of the deterministic verification tokens")
+	flag.StringVar(&owner, "owner", fake.DefaultOwner, "owner added to new verifications")
+	flag.StringVar(&published, "published", "always", "when insert finds the token: always, never, after:N or dns:HOST:PORT")
+	flag.StringVar(&removed, "removed", "always", "when delete finds the token removed: always, never, after:N or dns:HOST:PORT")
+	flag.IntVar(&failInsert, "fail-insert", 0, "fail the first N inserts with the token not found error")
+	flag.IntVar(&failDelete, "fail-delete", 0, "fail the first N deletes with the token still present error")
+	flag.Parse()
+
+	publishedCheck, err := parseCheck(published, false)
+	if err != nil {
+		log.Fatalf("invalid -published: %s", err)
+	}
+	removedCheck, err := parseCheck(removed, true)
+	if err != nil {
+		log.Fatalf("invalid -removed: %s", err)
+	}
+
+	s := fake.New(
+		fake.WithTokenSeed(seed),
+		fake.WithOwner(owner),
+		fake.WithPublishedCheck(publishedCheck),
+		fake.WithRemovedCheck(removedCheck),
+	)
+	if failInsert > 0 {
+		s.Inject(fake.MethodInsert, http.StatusBadRequest, fake.MessageTokenNotFound, failInsert)
+	}
+	if failDelete > 0 {
+		s.Inject(fake.MethodDelete, http.StatusBadRequest, fake.MessageTokenExists, failDelete)
+	}
+
+	log.Printf("serving the Site Verification API at http://%s/siteVerification/v1/", addr)
+	log.Fatal(http.ListenAndServe(addr, logRequests(s)))
+}
+
+// parseCheck parses a check specification. A DNS check of a removal passes
+// once the token is no longer published, and without a HOST:PORT it uses the
+// system resolver.
+func parseCheck(spec string, removal bool) (fake.Check, error) {
+	name, arg, hasArg := strings.Cut(spec, ":")
+	switch name {
+	case "always", "never":
+		if hasArg {
+			return nil, fmt.Errorf("%s takes no argument, got %q", name, spec)
+		}
+		if name == "always" {
+			return fake.Always(), nil
+		}
+		return fake.Never(), nil
+	case "after":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("expected after:N with a non-negative N, got %q", spec)
+		}
+		return fake.After(n), nil
+	case "dns":
+		if arg != "" {
+			if _, _, err := net.SplitHostPort(arg); err != nil {
+				return nil, fmt.Errorf("expected dns:HOST:PORT, got %q: %w", spec, err)
+			}
+		}
+		check := fake.DNS(arg)
+		if removal {
+			check = fake.Not(check)
+		}
+		return check, nil
+	}
+	return nil, fmt.Errorf("expected always, never, after:N or dns:HOST:PORT, got %q", spec)
+}
+
+func logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL.Path)
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"giautm.dev/googlesiteverification/internal/siteid"
+)
+
+func TestParseCheck(t *testing.T) {
+	domain := siteid.Site{Type: siteid.TypeDomain, Identifier: "example.com"}
+	// DNS checks report URL-prefix sites as not published without a lookup.
+	site := siteid.Site{Type: siteid.TypeSite, Identifier: "https://example.com/"}
+
+	for _, tt := range []struct {
+		spec    string
+		removal bool
+		site    siteid.Site
+		want    []bool
+		wantErr string
+	}{
+		{spec: "always", site: domain, want: []bool{true, true}},
+		{spec: "never", site: domain, want: []bool{false, false}},
+		{spec: "after:2", site: domain, want: []bool{false, false, true}},
+		{spec: "after:0", site: domain, want: []bool{true}},
+		{spec: "dns:127.0.0.1:53", site: site, want: []bool{false}},
+		{spec: "dns", site: site, want: []bool{false}},
+		{spec: "dns:127.0.0.1:53", removal: true, site: site, want: []bool{true}},
+		{spec: "after:2", removal: true, site: domain, want: []bool{false, false, true}},
+		{spec: "", wantErr: "expected always, never"},
+		{spec: "sometimes", wantErr: "expected always, never"},
+		{spec: "always:1", wantErr: "takes no argument"},
+		{spec: "after", wantErr: "expected after:N"},
+		{spec: "after:-1", wantErr: "expected after:N"},
+		{spec: "after:two", wantErr: "expected after:N"},
+		{spec: "dns:127.0.0.1", wantErr: "expected dns:HOST:PORT"},
+	} {
+		check, err := parseCheck(tt.spec, tt.removal)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseCheck(%q) returned error %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCheck(%q, %t) returned error: %s", tt.spec, tt.removal, err)
+			continue
+		}
+		for i, want := range tt.want {
+			if got, err := check(context.Background(), tt.site, "token"); err != nil || got != want {
+				t.Errorf("parseCheck(%q, %t) check %d = %t, %v, want %t", tt.spec, tt.removal, i+1, got, err, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,4 @@
+# Verifications can be imported by bare domain, by id, or by the URL-encoded id returned by the API.
+terraform import googlesiteverification_domain.example example.com
+terraform import googlesiteverification_domain.example dns://example.com
+terraform import googlesiteverification_domain.example dns%3A%2F%2Fexample.com
@@ -0,0 +1,232 @@
+// Package acctest provides stand-ins for the external services used by the
+// acceptance tests, so that they can replay without secrets.
+package acctest
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// CloudflareZoneID is the id of the zone served by the Cloudflare stub.
+const CloudflareZoneID = "023e105f4ecef8ad9ca31a8372d0c353"
+
+const cloudflareBasePath = "/client/v4/zones/" + CloudflareZoneID
+
+// CloudflareRecord is a DNS record stored by the Cloudflare stub.
+type CloudflareRecord struct {
+	ID         string    `json:"id"`
+	ZoneID     string    `json:"zone_id"`
+	ZoneName   string    `json:"zone_name"`
+	Type       string    `json:"type"`
+	Name       string    `json:"name"`
+	Content    string    `json:"content"`
+	TTL        int       `json:"ttl"`
+	Proxied    bool      `json:"proxied"`
+	Proxiable  bool      `json:"proxiable"`
+	Locked     bool      `json:"locked"`
+	CreatedOn  time.Time `json:"created_on"`
+	ModifiedOn time.Time `json:"modified_on"`
+}
+
+// Cloudflare is an HTTPS stub of the Cloudflare v4 API serving the DNS
+// records of a single zone, enough for the cloudflare_record resource.
+type Cloudflare struct {
+	// Host is the host:port the stub listens on.
+	Host string
+	// CertFile is a PEM file holding the certificate of the stub.
+	CertFile string
+	// BundleFile is a PEM file holding the CA bundle of the system followed
+	// by the certificate of the stub.
+	BundleFile string
+
+	zone string
+
+	mu      sync.Mutex
+	records map[string]CloudflareRecord
+	next    int
+}
+
+// NewCloudflare starts a Cloudflare stub serving zone, stopped when the
+// test ends.
+func NewCloudflare(t testing.TB, zone string) *Cloudflare {
+	t.Helper()
+
+	c := &Cloudflare{zone: zone, records: map[string]CloudflareRecord{}}
+	srv := httptest.NewTLSServer(c)
+	t.Cleanup(srv.Close)
+	c.Host = strings.TrimPrefix(srv.URL, "https://")
+
+	c.CertFile = filepath.Join(t.TempDir(), "cloudflare.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(c.CertFile, cert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c.BundleFile = filepath.Join(t.TempDir(), "bundle.pem")
+	if err := os.WriteFile(c.BundleFile, append(systemBundle(), cert...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// systemCertFiles are the CA bundles of common systems, as searched by
+// crypto/x509.
+var systemCertFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+// systemBundle returns the CA bundle of SSL_CERT_FILE or of the system, or
+// nothing when none is found.
+func systemBundle() []byte {
+	files := systemCertFiles
+	if f := os.Getenv("SSL_CERT_FILE"); f != "" {
+		files = []string{f}
+	}
+	for _, f := range files {
+		if data, err := os.ReadFile(f); err == nil {
+			return append(data, '\n')
+		}
+	}
+	return nil
+}
+
+// Setenv points the Cloudflare provider of the test at the stub. The
+// processes started by the test trust BundleFile, so that Terraform still
+// reaches the registry.
+func (c *Cloudflare) Setenv(t *testing.T) {
+	t.Setenv("CLOUDFLARE_API_HOSTNAME", c.Host)
+	t.Setenv("CLOUDFLARE_API_TOKEN", "stub-cloudflare-api-token-0000000000000")
+	t.Setenv("CLOUDFLARE_ZONE_ID", CloudflareZoneID)
+	t.Setenv("SSL_CERT_FILE", c.BundleFile)
+}
+
+// Records returns the records in the zone, sorted by id.
+func (c *Cloudflare) Records() []CloudflareRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	records := make([]CloudflareRecord, 0, len(c.records))
+	for _, r := range c.records {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records
+}
+
+func (c *Cloudflare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, cloudflareBasePath) {
+		writeCloudflareError(w, http.StatusNotFound, 7003, "Could not route to "+r.URL.Path)
+		return
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, cloudflareBasePath), "/")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		writeCloudflareResult(w, map[string]interface{}{
+			"id":     CloudflareZoneID,
+			"name":   c.zone,
+			"status": "active",
+			"plan":   map[string]interface{}{"id": "0feeeeeeeeeeeeeeeeeeeeeeeeeeeeee", "name": "Free Website", "legacy_id": "free"},
+		})
+	case rest == "dns_records" && r.Method == http.MethodGet:
+		records := make([]CloudflareRecord, 0, len(c.records))
+		for _, rec := range c.records {
+			if name := r.URL.Query().Get("name"); name != "" && name != rec.Name {
+				continue
+			}
+			if typ := r.URL.Query().Get("type"); typ != "" && typ != rec.Type {
+				continue
+			}
+			records = append(records, rec)
+		}
+		sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+		writeCloudflareResult(w, records)
+	case rest == "dns_records" && r.Method == http.MethodPost:
+		var rec CloudflareRecord
+		if err := json.NewDecoder(r.Body).Decode(&rec); err != nil {
+			writeCloudflareError(w, http.StatusBadRequest, 9207, "Request body is invalid.")
+			return
+		}
+		c.next++
+		now := time.Now().UTC()
+		rec.ID = fmt.Sprintf("%032x", c.next)
+		rec.ZoneID = CloudflareZoneID
+		rec.ZoneName = c.zone
+		rec.Name = c.fqdn(rec.Name)
+		if rec.TTL == 0 {
+			rec.TTL = 1
+		}
+		rec.CreatedOn, rec.ModifiedOn = now, now
+		c.records[rec.ID] = rec
+		writeCloudflareResult(w, rec)
+	case strings.HasPrefix(rest, "dns_records/"):
+		id := strings.TrimPrefix(rest, "dns_records/")
+		rec, ok := c.records[id]
+		if !ok {
+			writeCloudflareError(w, http.StatusNotFound, 81044, "Record does not exist.")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeCloudflareResult(w, rec)
+		case http.MethodDelete:
+			delete(c.records, id)
+			writeCloudflareResult(w, map[string]string{"id": id})
+		default:
+			writeCloudflareError(w, http.StatusMethodNotAllowed, 10000, "Method not allowed.")
+		}
+	default:
+		writeCloudflareError(w, http.StatusMethodNotAllowed, 10000, "Method not allowed.")
+	}
+}
+
+// fqdn returns name inside the zone, as Cloudflare stores record names.
+func (c *Cloudflare) fqdn(name string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	switch {
+	case name == "@" || name == "":
+		return c.zone
+	case name == c.zone || strings.HasSuffix(name, "."+c.zone):
+		return name
+	default:
+		return name + "." + c.zone
+	}
+}
+
+func writeCloudflareResult(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"errors":   []interface{}{},
+		"messages": []interface{}{},
+		"result":   result,
+	})
+}
+
+func writeCloudflareError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  false,
+		"errors":   []interface{}{map[string]interface{}{"code": code, "message": message}},
+		"messages": []interface{}{},
+		"result":   nil,
+	})
+}
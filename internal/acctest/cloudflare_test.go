@@ -0,0 +1,99 @@
+package acctest_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"giautm.dev/googlesiteverification/internal/acctest"
+)
+
+type cloudflareResponse struct {
+	Success bool                     `json:"success"`
+	Result  acctest.CloudflareRecord `json:"result"`
+}
+
+func TestCloudflare(t *testing.T) {
+	c := acctest.NewCloudflare(t, "example.com")
+	c.Setenv(t)
+	if os.Getenv("CLOUDFLARE_ZONE_ID") != acctest.CloudflareZoneID || os.Getenv("CLOUDFLARE_API_HOSTNAME") != c.Host {
+		t.Fatal("Setenv() did not point the Cloudflare provider at the stub")
+	}
+
+	pem, err := os.ReadFile(c.CertFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		t.Fatal("CertFile holds no certificate")
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	base := "https://" + c.Host + "/client/v4/zones/" + acctest.CloudflareZoneID
+
+	do := func(method, url, body string, wantStatus int) cloudflareResponse {
+		t.Helper()
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != wantStatus {
+			t.Fatalf("%s %s = %d, want %d", method, url, resp.StatusCode, wantStatus)
+		}
+		var got cloudflareResponse
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	created := do(http.MethodPost, base+"/dns_records", `{"type":"TXT","name":"www","content":"google-site-verification=abc","ttl":300}`, http.StatusOK)
+	if !created.Success || created.Result.Name != "www.example.com" || created.Result.ID == "" {
+		t.Errorf("create = %+v", created)
+	}
+	got := do(http.MethodGet, base+"/dns_records/"+created.Result.ID, "", http.StatusOK)
+	if got.Result.Content != "google-site-verification=abc" || got.Result.TTL != 300 {
+		t.Errorf("get = %+v", got)
+	}
+	if records := c.Records(); len(records) != 1 || records[0].ID != created.Result.ID {
+		t.Errorf("Records() = %+v", records)
+	}
+	do(http.MethodDelete, base+"/dns_records/"+created.Result.ID, "", http.StatusOK)
+	if gone := do(http.MethodGet, base+"/dns_records/"+created.Result.ID, "", http.StatusNotFound); gone.Success {
+		t.Errorf("get = %+v after delete", gone)
+	}
+	if len(c.Records()) != 0 {
+		t.Errorf("Records() = %+v after delete", c.Records())
+	}
+}
+
+func TestCloudflareBundle(t *testing.T) {
+	system, err := os.ReadFile("/etc/ssl/certs/ca-certificates.crt")
+	if err != nil {
+		t.Skip("No system CA bundle at /etc/ssl/certs/ca-certificates.crt.")
+	}
+	t.Setenv("SSL_CERT_FILE", "")
+	c := acctest.NewCloudflare(t, "example.com")
+	c.Setenv(t)
+
+	bundle, err := os.ReadFile(os.Getenv("SSL_CERT_FILE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := os.ReadFile(c.CertFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(bundle), string(system)) || !strings.HasSuffix(string(bundle), string(cert)) {
+		t.Error("SSL_CERT_FILE does not hold the system CA bundle followed by the stub certificate")
+	}
+}
@@ -0,0 +1,215 @@
+// Package apierror classifies the errors returned by the Site Verification
+// API.
+//
+// The API reports most failures as a 400 with an English sentence, so the
+// classification combines the status code, the reasons of Errors, the
+// google.rpc.ErrorInfo details and the status of the response body, and
+// only then falls back to keywords of the message.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Kind is the class of an API error.
+type Kind int
+
+const (
+	// Unknown is any error not recognized by Classify.
+	Unknown Kind = iota
+	// TokenNotFound means the verification token is not published yet.
+	TokenNotFound
+	// TokenStillPresent means the verification token must be removed
+	// before unverifying.
+	TokenStillPresent
+	// NotOwner means the caller is not an owner of the site.
+	NotOwner
+	// NotFound means the verification does not exist.
+	NotFound
+	// Quota means a rate limit or quota was exceeded.
+	Quota
+	// Transient means a server or network failure worth retrying.
+	Transient
+)
+
+var kindNames = map[Kind]string{
+	Unknown:           "unknown",
+	TokenNotFound:     "token not found",
+	TokenStillPresent: "token still present",
+	NotOwner:          "not owner",
+	NotFound:          "not found",
+	Quota:             "quota",
+	Transient:         "transient",
+}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Error is an API error with its class.
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+// The sentinel errors of each class, matched by errors.Is on the errors
+// returned by Wrap.
+var (
+	ErrTokenNotFound     = &Error{Kind: TokenNotFound}
+	ErrTokenStillPresent = &Error{Kind: TokenStillPresent}
+	ErrNotOwner          = &Error{Kind: NotOwner}
+	ErrNotFound          = &Error{Kind: NotFound}
+	ErrQuota             = &Error{Kind: Quota}
+	ErrTransient         = &Error{Kind: Transient}
+)
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.String()
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether target is the sentinel error of the class of e.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Err == nil && t.Kind == e.Kind
+}
+
+// Wrap returns err as an *Error when Classify recognizes it, and err
+// otherwise.
+func Wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+	kind := Classify(err)
+	if kind == Unknown {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// Classify returns the class of err, which is Unknown unless err is or wraps
+// a *googleapi.Error or an *Error.
+func Classify(err error) Kind {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Kind
+	}
+	var apierr *googleapi.Error
+	if !errors.As(err, &apierr) {
+		return Unknown
+	}
+
+	reasons := map[string]bool{}
+	for _, item := range apierr.Errors {
+		reasons[item.Reason] = true
+	}
+	for _, reason := range detailReasons(apierr.Details) {
+		reasons[reason] = true
+	}
+	status := bodyStatus(apierr.Body)
+	has := func(values ...string) bool {
+		for _, v := range values {
+			if reasons[v] || status == v {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case apierr.Code == http.StatusTooManyRequests,
+		has("rateLimitExceeded", "userRateLimitExceeded", "quotaExceeded", "dailyLimitExceeded",
+			"RATE_LIMIT_EXCEEDED", "RESOURCE_EXHAUSTED"):
+		return Quota
+	case apierr.Code >= http.StatusInternalServerError,
+		apierr.Code == http.StatusRequestTimeout,
+		has("backendError", "internalError", "serviceUnavailable", "UNAVAILABLE", "INTERNAL", "DEADLINE_EXCEEDED"):
+		return Transient
+	case apierr.Code == http.StatusNotFound, has("notFound", "NOT_FOUND"):
+		return NotFound
+	case apierr.Code == http.StatusForbidden:
+		if has("insufficientPermissions", "ACCESS_TOKEN_SCOPE_INSUFFICIENT", "SERVICE_DISABLED", "accessNotConfigured") {
+			return Unknown
+		}
+		return NotOwner
+	case apierr.Code == http.StatusBadRequest:
+		return classifyMessage(messages(apierr))
+	}
+	return Unknown
+}
+
+// classifyMessage tells apart the 400 errors about verification tokens,
+// which share the badRequest reason.
+func classifyMessage(msg string) Kind {
+	if !strings.Contains(msg, "token") {
+		return Unknown
+	}
+	switch {
+	case strings.Contains(msg, "unverify"),
+		strings.Contains(msg, "has been removed"),
+		strings.Contains(msg, "still present"):
+		return TokenStillPresent
+	case strings.Contains(msg, "could not be found"),
+		strings.Contains(msg, "not found"),
+		strings.Contains(msg, "could not find"):
+		return TokenNotFound
+	}
+	return Unknown
+}
+
+// messages returns the lowercased messages of apierr.
+func messages(apierr *googleapi.Error) string {
+	msgs := []string{apierr.Message}
+	for _, item := range apierr.Errors {
+		msgs = append(msgs, item.Message)
+	}
+	return strings.ToLower(strings.Join(msgs, "\n"))
+}
+
+// detailReasons returns the reasons of the google.rpc.ErrorInfo details.
+func detailReasons(details []interface{}) []string {
+	var reasons []string
+	for _, d := range details {
+		m, ok := d.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if typ, _ := m["@type"].(string); !strings.HasSuffix(typ, "google.rpc.ErrorInfo") {
+			continue
+		}
+		if reason, ok := m["reason"].(string); ok {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// bodyStatus returns the canonical status of a JSON error body, such as
+// RESOURCE_EXHAUSTED, which googleapi.Error does not keep.
+func bodyStatus(body string) string {
+	var reply struct {
+		Error struct {
+			Status string `json:"status"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(body), &reply) != nil {
+		return ""
+	}
+	return reply.Error.Status
+}
@@ -0,0 +1,124 @@
+package apierror_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+
+	"giautm.dev/googlesiteverification/internal/apierror"
+)
+
+// testResponseError returns the error the API client returns for a response
+// with the given status code and the body in testdata/file.
+func testResponseError(t *testing.T, code int, file string) error {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return googleapi.CheckResponse(&http.Response{
+		StatusCode: code,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(string(body))),
+	})
+}
+
+func TestClassify(t *testing.T) {
+	for _, tt := range []struct {
+		file string
+		code int
+		want apierror.Kind
+	}{
+		{file: "token_not_found.json", code: http.StatusBadRequest, want: apierror.TokenNotFound},
+		{file: "token_still_present.json", code: http.StatusBadRequest, want: apierror.TokenStillPresent},
+		{file: "not_owner.json", code: http.StatusForbidden, want: apierror.NotOwner},
+		{file: "not_found.json", code: http.StatusNotFound, want: apierror.NotFound},
+		{file: "rate_limit.json", code: http.StatusForbidden, want: apierror.Quota},
+		{file: "quota_exceeded.json", code: http.StatusTooManyRequests, want: apierror.Quota},
+		{file: "backend_error.json", code: http.StatusServiceUnavailable, want: apierror.Transient},
+		{file: "bad_gateway.html", code: http.StatusBadGateway, want: apierror.Transient},
+		{file: "insufficient_scopes.json", code: http.StatusForbidden, want: apierror.Unknown},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			err := testResponseError(t, tt.code, tt.file)
+			if got := apierror.Classify(err); got != tt.want {
+				t.Errorf("Classify() = %s, want %s", got, tt.want)
+			}
+			if got := apierror.Classify(fmt.Errorf("wrapped: %w", err)); got != tt.want {
+				t.Errorf("Classify() = %s for a wrapped error, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyMessage(t *testing.T) {
+	for _, tt := range []struct {
+		message string
+		want    apierror.Kind
+	}{
+		{message: "The verification token was not found in the DNS records of your site.", want: apierror.TokenNotFound},
+		{message: "We could not find the verification token.", want: apierror.TokenNotFound},
+		{message: "The verification token is still present on your site.", want: apierror.TokenStillPresent},
+		{message: "Invalid site identifier.", want: apierror.Unknown},
+	} {
+		err := &googleapi.Error{
+			Code:    http.StatusBadRequest,
+			Message: tt.message,
+			Errors:  []googleapi.ErrorItem{{Reason: "badRequest", Message: tt.message}},
+		}
+		if got := apierror.Classify(err); got != tt.want {
+			t.Errorf("Classify(%q) = %s, want %s", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyOther(t *testing.T) {
+	for _, err := range []error{
+		nil,
+		errors.New("boom"),
+		context.DeadlineExceeded,
+		&googleapi.Error{Code: http.StatusUnauthorized, Message: "Request had invalid authentication credentials."},
+	} {
+		if got := apierror.Classify(err); got != apierror.Unknown {
+			t.Errorf("Classify(%v) = %s, want unknown", err, got)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	err := testResponseError(t, http.StatusBadRequest, "token_not_found.json")
+	wrapped := apierror.Wrap(err)
+	if !errors.Is(wrapped, apierror.ErrTokenNotFound) {
+		t.Errorf("errors.Is(%v, ErrTokenNotFound) = false", wrapped)
+	}
+	if errors.Is(wrapped, apierror.ErrTokenStillPresent) {
+		t.Errorf("errors.Is(%v, ErrTokenStillPresent) = true", wrapped)
+	}
+	var apierr *googleapi.Error
+	if !errors.As(wrapped, &apierr) || apierr.Code != http.StatusBadRequest {
+		t.Errorf("errors.As(%v) did not find the googleapi.Error", wrapped)
+	}
+	if wrapped.Error() != err.Error() {
+		t.Errorf("Error() = %q, want %q", wrapped.Error(), err.Error())
+	}
+	if again := apierror.Wrap(wrapped); again != wrapped {
+		t.Errorf("Wrap() wrapped an *Error again: %v", again)
+	}
+
+	plain := errors.New("boom")
+	if got := apierror.Wrap(plain); got != plain {
+		t.Errorf("Wrap(%v) = %v, want it unchanged", plain, got)
+	}
+	if apierror.Wrap(nil) != nil {
+		t.Error("Wrap(nil) != nil")
+	}
+}
@@ -0,0 +1,261 @@
+// Package cassette records HTTP interactions to a JSON fixture and replays
+// them, so that acceptance tests can run without credentials or network.
+//
+// Recorded interactions are scrubbed before they are saved: verification
+// tokens and email addresses are replaced by stable placeholders, and
+// callers can add literal replacements such as a random test domain.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Cassette records or replays interactions.
+type Mode int
+
+const (
+	// ModeRecord sends requests to the network and saves the interactions.
+	ModeRecord Mode = iota
+	// ModeReplay answers requests from the saved interactions.
+	ModeReplay
+)
+
+type (
+	// Interaction is a recorded request and its response.
+	Interaction struct {
+		Request  Request  `json:"request"`
+		Response Response `json:"response"`
+	}
+	// Request is the recorded part of a request.
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Body   string `json:"body,omitempty"`
+	}
+	// Response is the recorded part of a response.
+	Response struct {
+		StatusCode  int    `json:"status_code"`
+		ContentType string `json:"content_type,omitempty"`
+		Body        string `json:"body,omitempty"`
+	}
+)
+
+// Cassette holds the interactions of one test. It is safe for concurrent
+// use.
+type Cassette struct {
+	path     string
+	mode     Mode
+	replacer *strings.Replacer
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+	last         map[string]int
+}
+
+// New returns a cassette stored at path. In replay mode the interactions
+// are loaded from path; replacements are old, new pairs applied to recorded
+// interactions, as with strings.NewReplacer.
+func New(path string, mode Mode, replacements ...string) (*Cassette, error) {
+	if len(replacements)%2 == 1 {
+		return nil, fmt.Errorf("cassette: odd number of replacements")
+	}
+	c := &Cassette{
+		path:     path,
+		mode:     mode,
+		replacer: strings.NewReplacer(replacements...),
+		last:     map[string]int{},
+	}
+	if mode == ModeReplay {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &c.interactions); err != nil {
+			return nil, fmt.Errorf("cassette: parsing %s: %w", path, err)
+		}
+		c.used = make([]bool, len(c.interactions))
+	}
+	return c, nil
+}
+
+// Transport returns a round tripper that records the requests sent through
+// base, or replays them without using base.
+func (c *Cassette) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{cassette: c, base: base}
+}
+
+// Interactions returns the interactions recorded or loaded so far.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// Save scrubs the recorded interactions and writes them to the cassette
+// path. It does nothing in replay mode.
+func (c *Cassette) Save() error {
+	if c.mode != ModeRecord {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := json.MarshalIndent(Scrub(c.interactions, c.replacer), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(b, '\n'), 0o644)
+}
+
+func (c *Cassette) record(i Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, i)
+}
+
+// replay returns the first unused interaction matching req. Once every
+// matching interaction is used the last one is returned again, so that
+// polling the same request more often than when recording still works.
+func (c *Cassette) replay(req Request) (Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := req.Method + " " + req.URL + "\n" + req.Body
+	for n, i := range c.interactions {
+		if !c.used[n] && i.Request == req {
+			c.used[n] = true
+			c.last[key] = n
+			return i.Response, nil
+		}
+	}
+	if n, ok := c.last[key]; ok {
+		return c.interactions[n].Response, nil
+	}
+	return Response{}, fmt.Errorf("cassette: no interaction recorded in %s for %s %s", c.path, req.Method, req.URL)
+}
+
+type transport struct {
+	cassette *Cassette
+	base     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	recorded := Request{Method: req.Method, URL: req.URL.String(), Body: string(body)}
+
+	if t.cassette.mode == ModeReplay {
+		r, err := t.cassette.replay(recorded)
+		if err != nil {
+			return nil, err
+		}
+		return r.response(req), nil
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	r := Response{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(b),
+	}
+	t.cassette.record(Interaction{Request: recorded, Response: r})
+	return r.response(req), nil
+}
+
+func (r Response) response(req *http.Request) *http.Response {
+	h := http.Header{}
+	if r.ContentType != "" {
+		h.Set("Content-Type", r.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+var (
+	tokenRe = regexp.MustCompile(`google-site-verification=[A-Za-z0-9_-]+`)
+	emailRe = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// Scrub returns a copy of interactions with replacer applied, then every
+// verification token and email address replaced by a numbered placeholder.
+// The same value gets the same placeholder across all interactions.
+func Scrub(interactions []Interaction, replacer *strings.Replacer) []Interaction {
+	tokens := placeholders(func(n int) string {
+		return fmt.Sprintf("google-site-verification=scrubbed-token-%d", n)
+	})
+	emails := placeholders(func(n int) string {
+		return fmt.Sprintf("owner-%d@example.com", n)
+	})
+	scrub := func(s string) string {
+		if replacer != nil {
+			s = replacer.Replace(s)
+		}
+		s = tokenRe.ReplaceAllStringFunc(s, tokens)
+		return emailRe.ReplaceAllStringFunc(s, emails)
+	}
+
+	scrubbed := make([]Interaction, len(interactions))
+	for n, i := range interactions {
+		i.Request.URL = scrub(i.Request.URL)
+		i.Request.Body = scrub(i.Request.Body)
+		i.Response.Body = scrub(i.Response.Body)
+		scrubbed[n] = i
+	}
+	return scrubbed
+}
+
+// placeholders returns a function mapping each distinct value to the
+// placeholder of the order it was first seen in.
+func placeholders(format func(n int) string) func(string) string {
+	seen := map[string]string{}
+	return func(s string) string {
+		p, ok := seen[s]
+		if !ok {
+			p = format(len(seen) + 1)
+			seen[s] = p
+		}
+		return p
+	}
+}
@@ -0,0 +1,139 @@
+package cassette_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/siteverification/v1"
+
+	"giautm.dev/googlesiteverification/internal/cassette"
+	"giautm.dev/googlesiteverification/internal/fake"
+)
+
+func testService(t *testing.T, c *cassette.Cassette, endpoint string) *siteverification.Service {
+	t.Helper()
+
+	svc, err := siteverification.NewService(context.Background(),
+		option.WithHTTPClient(&http.Client{Transport: c.Transport(nil)}),
+		option.WithEndpoint(endpoint),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+func getToken(svc *siteverification.Service, domain string) (string, error) {
+	resp, err := svc.WebResource.GetToken(&siteverification.SiteVerificationWebResourceGettokenRequest{
+		Site: &siteverification.SiteVerificationWebResourceGettokenRequestSite{
+			Identifier: domain,
+			Type:       "INET_DOMAIN",
+		},
+		VerificationMethod: "DNS_TXT",
+	}).Do()
+	if err != nil {
+		return "", err
+	}
+	return resp.Token, nil
+}
+
+func TestRecordReplay(t *testing.T) {
+	const (
+		domain = "0f8fad5b-test.example.com"
+		fixed  = "replay-test.example.com"
+	)
+	s := fake.New(fake.WithOwner("someone@example.org"))
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	path := filepath.Join(t.TempDir(), "testdata", "cassette.json")
+
+	rec, err := cassette.New(path, cassette.ModeRecord, domain, fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := testService(t, rec, srv.URL+"/")
+	token, err := getToken(svc, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != s.Token(domain) {
+		t.Fatalf("getToken() = %q while recording, want %q", token, s.Token(domain))
+	}
+	if _, err := svc.WebResource.Insert("DNS_TXT", &siteverification.SiteVerificationWebResourceResource{
+		Site: &siteverification.SiteVerificationWebResourceResourceSite{Identifier: domain, Type: "INET_DOMAIN"},
+	}).Do(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{domain, token, "someone@example.org"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("saved cassette contains %q:\n%s", secret, b)
+		}
+	}
+
+	play, err := cassette.New(path, cassette.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	svc = testService(t, play, srv.URL+"/")
+	for i := 0; i < 2; i++ {
+		token, err = getToken(svc, fixed)
+		if err != nil {
+			t.Fatalf("getToken() returned error on replay %d: %s", i, err)
+		}
+		if token != "google-site-verification=scrubbed-token-1" {
+			t.Errorf("getToken() = %q on replay, want the scrubbed token", token)
+		}
+	}
+	res, err := svc.WebResource.Insert("DNS_TXT", &siteverification.SiteVerificationWebResourceResource{
+		Site: &siteverification.SiteVerificationWebResourceResourceSite{Identifier: fixed, Type: "INET_DOMAIN"},
+	}).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Site.Identifier != fixed || len(res.Owners) != 1 || res.Owners[0] != "owner-1@example.com" {
+		t.Errorf("insert() = %+v on replay", res)
+	}
+	if _, err := getToken(svc, "other.example.com"); err == nil || !strings.Contains(err.Error(), "no interaction recorded") {
+		t.Errorf("getToken() returned error %v for an unrecorded request", err)
+	}
+}
+
+func TestScrub(t *testing.T) {
+	got := cassette.Scrub([]cassette.Interaction{
+		{Response: cassette.Response{Body: `{"token":"google-site-verification=abc_-1","owners":["a@example.org","b@example.org"]}`}},
+		{Request: cassette.Request{Body: `{"owners":["b@example.org"]}`}, Response: cassette.Response{Body: `google-site-verification=abc_-1 google-site-verification=xyz`}},
+	}, nil)
+
+	for _, tt := range []struct {
+		got, want string
+	}{
+		{got[0].Response.Body, `{"token":"google-site-verification=scrubbed-token-1","owners":["owner-1@example.com","owner-2@example.com"]}`},
+		{got[1].Request.Body, `{"owners":["owner-2@example.com"]}`},
+		{got[1].Response.Body, `google-site-verification=scrubbed-token-1 google-site-verification=scrubbed-token-2`},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("Scrub() = %s, want %s", tt.got, tt.want)
+		}
+	}
+}
+
+func TestNewMissing(t *testing.T) {
+	if _, err := cassette.New(filepath.Join(t.TempDir(), "missing.json"), cassette.ModeReplay); !os.IsNotExist(err) {
+		t.Errorf("New() returned error %v, want a not exist error", err)
+	}
+}
@@ -0,0 +1,443 @@
+// Package cli implements the subcommands of the provider binary, which call
+// the Site Verification API outside Terraform with the credentials the
+// provider would use:
+//
+//	terraform-provider-googlesiteverification token example.com
+//	terraform-provider-googlesiteverification verify example.com --wait
+//
+// Without a subcommand, the binary serves the provider plugin.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/api/siteverification/v1"
+
+	"giautm.dev/googlesiteverification/internal/apierror"
+	"giautm.dev/googlesiteverification/internal/domainname"
+	"giautm.dev/googlesiteverification/internal/provider"
+	"giautm.dev/googlesiteverification/internal/siteid"
+	"giautm.dev/googlesiteverification/verifier"
+)
+
+const (
+	program = "terraform-provider-googlesiteverification"
+
+	methodDNSCNAME = "DNS_CNAME"
+
+	// defaultTimeout is how long verify waits with --wait, like the default
+	// create timeout of googlesiteverification_domain.
+	defaultTimeout = 5 * verifier.DefaultRetryInterval
+)
+
+// client is shared by the commands.
+type client struct {
+	config provider.ClientConfig
+	srv    *siteverification.Service
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// service returns the API client, created on first use so that invalid
+// arguments are reported before the credentials are resolved.
+func (c *client) service(ctx context.Context) (*siteverification.Service, error) {
+	if c.srv != nil {
+		return c.srv, nil
+	}
+	srv, err := provider.NewService(ctx, c.config)
+	if err != nil {
+		return nil, err
+	}
+	c.srv = srv
+	return srv, nil
+}
+
+func (c *client) api(ctx context.Context) (verifier.API, error) {
+	srv, err := c.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return verifier.NewAPI(srv), nil
+}
+
+// usageError is returned by commands called with invalid arguments.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+type command struct {
+	args    string
+	summary string
+	// flags defines the flags of the command, and returns the function
+	// running it with the positional arguments.
+	flags func(fs *flag.FlagSet) func(ctx context.Context, c *client, args []string) error
+}
+
+var commands = map[string]command{
+	"token": {
+		args:    "<domain> [--method dns_txt|dns_cname]",
+		summary: "Print the verification token of a domain.",
+		flags:   tokenCommand,
+	},
+	"verify": {
+		args:    "<domain> [--wait]",
+		summary: "Verify a domain whose token is published in DNS, and print its id.",
+		flags:   verifyCommand,
+	},
+	"unverify": {
+		args:    "<domain>",
+		summary: "Unverify a domain whose token was removed from DNS.",
+		flags:   unverifyCommand,
+	},
+	"list": {
+		args:    "[--json]",
+		summary: "List the verifications owned by the credentials.",
+		flags:   listCommand,
+	},
+	"owners": {
+		args:    "<id> [add|remove <email>]",
+		summary: "Print, add or remove the owners of a verification.",
+		flags:   ownersCommand,
+	},
+}
+
+// IsCommand reports whether args, without the program name, start with a
+// subcommand rather than the flags of the plugin server.
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	_, ok := commands[args[0]]
+	return ok || args[0] == "help"
+}
+
+// Run runs the subcommand in args, without the program name, and returns
+// the exit code: 1 when the command fails and 2 for invalid arguments.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" {
+		usage(stderr)
+		return 0
+	}
+	name := args[0]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown command %q.\n\n", name)
+		usage(stderr)
+		return 2
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s %s %s\n\n%s\n\nFlags:\n", program, name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+	var config provider.ClientConfig
+	fs.StringVar(&config.Credentials, "credentials", "", "the path to or the contents of a credentials file, like the credentials provider attribute")
+	fs.StringVar(&config.AccessToken, "access-token", "", "a temporary OAuth 2.0 access token, like the access_token provider attribute")
+	fs.StringVar(&config.ImpersonateServiceAccount, "impersonate-service-account", "", "the email of a service account to impersonate")
+	fs.Var((*listValue)(&config.ImpersonateServiceAccountDelegates), "impersonate-service-account-delegates", "the comma-separated emails of the delegation chain leading to --impersonate-service-account")
+	fs.StringVar(&config.IAMCredentialsEndpoint, "iam-credentials-endpoint", "", "the base URL of the IAM Credentials API used by --impersonate-service-account")
+	fs.StringVar(&config.Subject, "subject", "", "the email of a Google Workspace user to act as through domain-wide delegation")
+	fs.Var((*listValue)(&config.Scopes), "scopes", "the comma-separated OAuth 2.0 scopes to request")
+	fs.BoolVar(&config.UserProjectOverride, "user-project-override", false, "bill API quota to --billing-project")
+	fs.StringVar(&config.BillingProject, "billing-project", "", "the project billed for API quota with --user-project-override")
+	fs.StringVar(&config.RequestReason, "request-reason", "", "a justification recorded in Cloud Audit Logs")
+	fs.StringVar(&config.Endpoint, "endpoint", "", "the base URL of the Site Verification API")
+	fs.BoolVar(&config.Emulator, "emulator", false, "send requests to --endpoint without authentication")
+	run := cmd.flags(fs)
+
+	positional, err := parse(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+
+	c := &client{config: config, stdout: stdout, stderr: stderr}
+	err = run(ctx, c, positional)
+	var uerr usageError
+	switch {
+	case errors.As(err, &uerr):
+		fmt.Fprintf(stderr, "Error: %s\n\n", err)
+		fs.Usage()
+		return 2
+	case err != nil:
+		fmt.Fprintf(stderr, "Error: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Usage: %s <command> [arguments]\n\n", program)
+	fmt.Fprintf(w, "Without a command, the Terraform provider plugin is served.\n\nCommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", name, commands[name].args, commands[name].summary)
+	}
+	_ = tw.Flush()
+	fmt.Fprintf(w, "\nCredentials are resolved like the provider configuration, from the environment unless set by flags.\n")
+}
+
+// listValue is a flag holding comma-separated values, which may be repeated.
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// parse parses flags placed before, between or after the positional
+// arguments, which are returned.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func tokenCommand(fs *flag.FlagSet) func(ctx context.Context, c *client, args []string) error {
+	method := fs.String("method", "dns_txt", "the verification method: dns_txt or dns_cname")
+	return func(ctx context.Context, c *client, args []string) error {
+		if len(args) != 1 {
+			return usageError("expected a domain")
+		}
+		m := strings.ToUpper(*method)
+		if m != verifier.MethodDNSTXT && m != methodDNSCNAME {
+			return usageError(fmt.Sprintf("unsupported method %q, expected dns_txt or dns_cname", *method))
+		}
+		domain, err := domainname.Normalize(args[0])
+		if err != nil {
+			return err
+		}
+		srv, err := c.service(ctx)
+		if err != nil {
+			return err
+		}
+		result, err := srv.WebResource.
+			GetToken(&siteverification.SiteVerificationWebResourceGettokenRequest{
+				Site: &siteverification.SiteVerificationWebResourceGettokenRequestSite{
+					Identifier: domain,
+					Type:       verifier.TypeDomain,
+				},
+				VerificationMethod: m,
+			}).
+			Context(ctx).Do()
+		if err != nil {
+			return apierror.Wrap(err)
+		}
+		fmt.Fprintln(c.stdout, result.Token)
+		return nil
+	}
+}
+
+func verifyCommand(fs *flag.FlagSet) func(ctx context.Context, c *client, args []string) error {
+	wait := fs.Bool("wait", false, "retry until the token is visible in DNS, instead of failing")
+	timeout := fs.Duration("timeout", defaultTimeout, "how long to retry with --wait")
+	interval := fs.Duration("interval", verifier.DefaultRetryInterval, "how long to wait between attempts with --wait")
+	return func(ctx context.Context, c *client, args []string) error {
+		if len(args) != 1 {
+			return usageError("expected a domain")
+		}
+		var id string
+		err := c.attempt(ctx, *wait, *timeout, *interval, func(ctx context.Context, v *verifier.Verifier) (err error) {
+			id, err = v.Verify(ctx, args[0])
+			return err
+		})
+		if errors.Is(err, verifier.ErrTokenNotFound) && !*wait {
+			return fmt.Errorf("%w\nPublish the token printed by the token command as a TXT record of the domain, or retry with --wait", err)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.stdout, id)
+		return nil
+	}
+}
+
+func unverifyCommand(fs *flag.FlagSet) func(ctx context.Context, c *client, args []string) error {
+	return func(ctx context.Context, c *client, args []string) error {
+		if len(args) != 1 {
+			return usageError("expected a domain")
+		}
+		id, err := normalizeID(args[0])
+		if err != nil {
+			return err
+		}
+		err = c.attempt(ctx, false, 0, 0, func(ctx context.Context, v *verifier.Verifier) error {
+			return v.Unverify(ctx, id)
+		})
+		if errors.Is(err, verifier.ErrTokenStillPresent) {
+			return fmt.Errorf("%w\nRemove the TXT record of the token from the domain first", err)
+		}
+		return err
+	}
+}
+
+// normalizeID returns the id of the verification of a domain or an id, with
+// the domain normalized like the domain of googlesiteverification_domain.
+// URL-prefix ids are returned unchanged.
+func normalizeID(arg string) (string, error) {
+	site, err := siteid.Parse(arg)
+	if err != nil {
+		return "", err
+	}
+	if !site.IsDomain() {
+		return site.ID(), nil
+	}
+	domain, err := domainname.Normalize(site.Identifier)
+	if err != nil {
+		return "", err
+	}
+	return siteid.Site{Type: siteid.TypeDomain, Identifier: domain}.ID(), nil
+}
+
+// attempt calls fn with a Verifier that retries for timeout with wait, and
+// otherwise returns the first error that would be retried.
+func (c *client) attempt(ctx context.Context, wait bool, timeout, interval time.Duration, fn func(context.Context, *verifier.Verifier) error) error {
+	api, err := c.api(ctx)
+	if err != nil {
+		return err
+	}
+	if wait {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return fn(ctx, verifier.New(api,
+			verifier.WithRetryInterval(interval),
+			verifier.WithRetryHook(func(ctx context.Context, domain string, err error) {
+				fmt.Fprintf(c.stderr, "Waiting for %s: %s\n", domain, err)
+			}),
+		))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var retried error
+	err = fn(ctx, verifier.New(api, verifier.WithRetryHook(func(ctx context.Context, domain string, err error) {
+		retried = err
+		cancel()
+	})))
+	if retried != nil {
+		return retried
+	}
+	return err
+}
+
+// site is a verification printed by the list command.
+type site struct {
+	ID     string   `json:"id"`
+	Type   string   `json:"type"`
+	Owners []string `json:"owners"`
+}
+
+func listCommand(fs *flag.FlagSet) func(ctx context.Context, c *client, args []string) error {
+	asJSON := fs.Bool("json", false, "print the verifications as JSON")
+	return func(ctx context.Context, c *client, args []string) error {
+		if len(args) != 0 {
+			return usageError("unexpected arguments")
+		}
+		api, err := c.api(ctx)
+		if err != nil {
+			return err
+		}
+		items, err := api.List(ctx)
+		if err != nil {
+			return apierror.Wrap(err)
+		}
+		sites := make([]site, 0, len(items))
+		for _, item := range items {
+			s := site{ID: item.Id, Owners: item.Owners}
+			if parsed, err := siteid.Parse(item.Id); err == nil {
+				s.ID = parsed.ID()
+			}
+			if item.Site != nil {
+				s.Type = item.Site.Type
+			}
+			if s.Owners == nil {
+				s.Owners = []string{}
+			}
+			sites = append(sites, s)
+		}
+		sort.Slice(sites, func(i, j int) bool { return sites[i].ID < sites[j].ID })
+
+		if *asJSON {
+			enc := json.NewEncoder(c.stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(sites)
+		}
+		tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTYPE\tOWNERS")
+		for _, s := range sites {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", s.ID, s.Type, strings.Join(s.Owners, ","))
+		}
+		return tw.Flush()
+	}
+}
+
+func ownersCommand(fs *flag.FlagSet) func(ctx context.Context, c *client, args []string) error {
+	return func(ctx context.Context, c *client, args []string) error {
+		if len(args) != 1 && (len(args) != 3 || (args[1] != "add" && args[1] != "remove")) {
+			return usageError("expected an id, optionally followed by add or remove and an email")
+		}
+		api, err := c.api(ctx)
+		if err != nil {
+			return err
+		}
+		id, err := normalizeID(args[0])
+		if err != nil {
+			return err
+		}
+		v := verifier.New(api)
+		var owners []string
+		switch {
+		case len(args) == 1:
+			owners, err = v.Owners(ctx, id)
+		case args[1] == "add":
+			owners, err = v.AddOwner(ctx, id, args[2])
+		default:
+			owners, err = v.RemoveOwner(ctx, id, args[2])
+		}
+		if err != nil {
+			return err
+		}
+		for _, owner := range owners {
+			fmt.Fprintln(c.stdout, owner)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,178 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"giautm.dev/googlesiteverification/internal/cli"
+	"giautm.dev/googlesiteverification/internal/fake"
+)
+
+// run runs a command against s and returns its exit code and output.
+func run(t *testing.T, s *fake.Server, args ...string) (int, string, string) {
+	t.Helper()
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	args = append(args, "--emulator", "--endpoint", srv.URL+"/siteVerification/v1/")
+	var stdout, stderr bytes.Buffer
+	code := cli.Run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestIsCommand(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{args: nil, want: false},
+		{args: []string{"-debug"}, want: false},
+		{args: []string{"-protocol", "5"}, want: false},
+		{args: []string{"token", "example.com"}, want: true},
+		{args: []string{"help"}, want: true},
+	} {
+		if got := cli.IsCommand(tt.args); got != tt.want {
+			t.Errorf("IsCommand(%q) = %t, want %t", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestToken(t *testing.T) {
+	s := fake.New()
+	code, stdout, stderr := run(t, s, "token", "Example.com.")
+	if code != 0 || stdout != s.Token("example.com")+"\n" {
+		t.Errorf("token = %d, %q, %q", code, stdout, stderr)
+	}
+	scopes := "https://www.googleapis.com/auth/siteverification, https://www.googleapis.com/auth/siteverification.verify_only"
+	if code, _, stderr := run(t, s, "token", "example.com", "--scopes", scopes); code != 0 {
+		t.Errorf("token --scopes = %d, %q", code, stderr)
+	}
+	if code, _, stderr := run(t, s, "token", "--method", "dns_cname", "example.com"); code != 0 {
+		t.Errorf("token --method dns_cname = %d, %q", code, stderr)
+	}
+	if code, _, stderr := run(t, s, "token", "example.com", "--method", "file"); code != 2 || !strings.Contains(stderr, "unsupported method") {
+		t.Errorf("token --method file = %d, %q", code, stderr)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	s := fake.New(fake.WithPublishedCheck(fake.After(2)))
+	code, _, stderr := run(t, s, "verify", "example.com")
+	if code != 1 || !strings.Contains(stderr, "retry with --wait") || s.Calls(fake.MethodInsert) != 1 {
+		t.Errorf("verify = %d, %q, inserts %d", code, stderr, s.Calls(fake.MethodInsert))
+	}
+
+	code, stdout, stderr := run(t, s, "verify", "example.com", "--wait", "--interval", "1ms")
+	if code != 0 || stdout != "dns://example.com\n" || !s.Verified("example.com") {
+		t.Errorf("verify --wait = %d, %q, %q", code, stdout, stderr)
+	}
+	if !strings.Contains(stderr, "Waiting for example.com") {
+		t.Errorf("verify --wait printed %q, want the retried errors", stderr)
+	}
+}
+
+func TestUnverify(t *testing.T) {
+	s := fake.New(fake.WithRemovedCheck(fake.After(1)))
+	s.Put("example.com")
+	code, _, stderr := run(t, s, "unverify", "example.com")
+	if code != 1 || !strings.Contains(stderr, "Remove the TXT record") {
+		t.Errorf("unverify = %d, %q", code, stderr)
+	}
+	if code, _, stderr := run(t, s, "unverify", "dns://example.com"); code != 0 || s.Verified("example.com") {
+		t.Errorf("unverify = %d, %q", code, stderr)
+	}
+
+	s = fake.New()
+	s.Put("xn--bcher-kva.example.org")
+	if code, _, stderr := run(t, s, "unverify", "dns://Bücher.Example.ORG."); code != 0 || s.Verified("xn--bcher-kva.example.org") {
+		t.Errorf("unverify of an unnormalized domain = %d, %q", code, stderr)
+	}
+}
+
+func TestList(t *testing.T) {
+	s := fake.New()
+	s.Put("b.example.com", "a@example.com", "b@example.com")
+	s.Put("a.example.com", "a@example.com")
+
+	code, stdout, stderr := run(t, s, "list")
+	if code != 0 {
+		t.Fatalf("list = %d, %q", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "dns://a.example.com") || !strings.HasSuffix(lines[2], "a@example.com,b@example.com") {
+		t.Errorf("list printed %q", stdout)
+	}
+
+	code, stdout, stderr = run(t, s, "list", "--json")
+	if code != 0 {
+		t.Fatalf("list --json = %d, %q", code, stderr)
+	}
+	var sites []struct {
+		ID     string   `json:"id"`
+		Type   string   `json:"type"`
+		Owners []string `json:"owners"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &sites); err != nil {
+		t.Fatal(err)
+	}
+	if len(sites) != 2 || sites[0].ID != "dns://a.example.com" || sites[0].Type != "INET_DOMAIN" || len(sites[1].Owners) != 2 {
+		t.Errorf("list --json printed %s", stdout)
+	}
+}
+
+func TestOwners(t *testing.T) {
+	s := fake.New()
+	s.Put("example.com", "a@example.com")
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"owners", "example.com"}, want: "a@example.com\n"},
+		{args: []string{"owners", "example.com", "add", "b@example.com"}, want: "a@example.com\nb@example.com\n"},
+		{args: []string{"owners", "dns://example.com", "remove", "a@example.com"}, want: "b@example.com\n"},
+		{args: []string{"owners", "Example.COM."}, want: "b@example.com\n"},
+	} {
+		code, stdout, stderr := run(t, s, tt.args...)
+		if code != 0 || stdout != tt.want {
+			t.Errorf("%q = %d, %q, %q, want %q", tt.args, code, stdout, stderr, tt.want)
+		}
+	}
+	if code, _, stderr := run(t, s, "owners", "example.com", "transfer", "b@example.com"); code != 2 {
+		t.Errorf("owners transfer = %d, %q", code, stderr)
+	}
+	if code, _, stderr := run(t, s, "owners", "missing.example.com"); code != 1 {
+		t.Errorf("owners of a missing verification = %d, %q", code, stderr)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     string
+	}{
+		{name: "unknown", args: []string{"transfer"}, wantCode: 2, want: "Unknown command"},
+		{name: "help", args: []string{"help"}, wantCode: 0, want: "verify <domain> [--wait]"},
+		{name: "command help", args: []string{"verify", "-h"}, wantCode: 0, want: "-wait"},
+		{name: "missing domain", args: []string{"token"}, wantCode: 2, want: "expected a domain"},
+		{name: "unknown flag", args: []string{"list", "--yaml"}, wantCode: 2, want: "flag provided but not defined"},
+		{name: "emulator without endpoint", args: []string{"list", "--emulator"}, wantCode: 1, want: "Missing endpoint"},
+		{name: "invalid scope", args: []string{"list", "--scopes", "https://www.googleapis.com/auth/cloud-platform"}, wantCode: 1, want: "Invalid Scope"},
+		{name: "billing project", args: []string{"list", "--user-project-override"}, wantCode: 1, want: "Missing billing_project"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOOGLE_SITEVERIFICATION_ENDPOINT", "")
+			var stdout, stderr bytes.Buffer
+			code := cli.Run(context.Background(), tt.args, &stdout, &stderr)
+			if code != tt.wantCode || !strings.Contains(stderr.String(), tt.want) {
+				t.Errorf("Run(%q) = %d, %q, want %d and %q", tt.args, code, stderr.String(), tt.wantCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package domainname normalizes and validates the domain names passed to the
+// Site Verification API.
+package domainname
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ErrPublicSuffix is returned for names that are a public suffix, such as
+// "com" or "co.uk", and so cannot be owned by anyone.
+var ErrPublicSuffix = errors.New("domain is a public suffix")
+
+var lookup = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.VerifyDNSLength(true),
+	idna.Transitional(false),
+)
+
+// Normalize returns the canonical ASCII form of name: lowercased, without a
+// trailing dot, and with internationalized labels converted to punycode.
+func Normalize(name string) (string, error) {
+	ascii, err := Canonical(name)
+	if err != nil {
+		return "", err
+	}
+	if suffix, _ := publicsuffix.PublicSuffix(ascii); suffix == ascii {
+		return "", fmt.Errorf("invalid domain %q: %w", name, ErrPublicSuffix)
+	}
+	return ascii, nil
+}
+
+// Canonical is like Normalize, but accepts public suffixes, for example to
+// suggest a domain registered under one.
+func Canonical(name string) (string, error) {
+	trimmed := strings.TrimSuffix(strings.TrimSpace(name), ".")
+	if trimmed == "" {
+		return "", errors.New("domain must not be empty")
+	}
+	ascii, err := lookup.ToASCII(trimmed)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain %q: %w", name, err)
+	}
+	return ascii, nil
+}
+
+// Equal reports whether a and b normalize to the same name.
+func Equal(a, b string) bool {
+	na, errA := Normalize(a)
+	nb, errB := Normalize(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return na == nb
+}
+
+// Relative returns name relative to zone, or "@" when name is the zone apex.
+// Both arguments must already be normalized.
+func Relative(name, zone string) (string, error) {
+	if name == zone {
+		return "@", nil
+	}
+	if rel := strings.TrimSuffix(name, "."+zone); rel != name && rel != "" {
+		return rel, nil
+	}
+	return "", fmt.Errorf("domain %q is not inside zone %q", name, zone)
+}
+
+// FQDN returns name as a fully qualified domain name with a trailing dot.
+func FQDN(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
@@ -0,0 +1,121 @@
+package domainname_test
+
+import (
+	"errors"
+	"testing"
+
+	"giautm.dev/googlesiteverification/internal/domainname"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"example.com", "example.com"},
+		{"Example.COM", "example.com"},
+		{"example.com.", "example.com"},
+		{" sub.Example.com. ", "sub.example.com"},
+		{"bücher.de", "xn--bcher-kva.de"},
+		{"BÜCHER.de.", "xn--bcher-kva.de"},
+		{"xn--bcher-kva.de", "xn--bcher-kva.de"},
+		{"example.co.uk", "example.co.uk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := domainname.Normalize(tt.in)
+			if err != nil {
+				t.Fatalf("Normalize(%q) returned error: %s", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		".",
+		"exa mple.com",
+		"under_score.example.com",
+		"-leading.example.com",
+		"a..example.com",
+	} {
+		t.Run(in, func(t *testing.T) {
+			if got, err := domainname.Normalize(in); err == nil {
+				t.Errorf("Normalize(%q) = %q, want error", in, got)
+			}
+		})
+	}
+}
+
+func TestNormalizePublicSuffix(t *testing.T) {
+	for _, in := range []string{"com", "co.uk", "CO.UK.", "github.io"} {
+		t.Run(in, func(t *testing.T) {
+			if _, err := domainname.Normalize(in); !errors.Is(err, domainname.ErrPublicSuffix) {
+				t.Errorf("Normalize(%q) error = %v, want ErrPublicSuffix", in, err)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	for in, want := range map[string]string{"CO.UK.": "co.uk", "Example.com": "example.com", "公司.cn": "xn--55qx5d.cn"} {
+		if got, err := domainname.Canonical(in); err != nil || got != want {
+			t.Errorf("Canonical(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := domainname.Canonical(" . "); err == nil {
+		t.Error("Canonical() returned no error for an empty domain")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	if !domainname.Equal("Example.com.", "example.com") {
+		t.Error("Equal(\"Example.com.\", \"example.com\") = false, want true")
+	}
+	if domainname.Equal("example.com", "example.org") {
+		t.Error("Equal(\"example.com\", \"example.org\") = true, want false")
+	}
+}
+
+func TestRelative(t *testing.T) {
+	tests := []struct {
+		name, zone, want string
+	}{
+		{"app.eu.example.com", "example.com", "app.eu"},
+		{"example.com", "example.com", "@"},
+		{"eu.example.com", "eu.example.com", "@"},
+		{"app.eu.example.com", "eu.example.com", "app"},
+	}
+	for _, tt := range tests {
+		got, err := domainname.Relative(tt.name, tt.zone)
+		if err != nil {
+			t.Fatalf("Relative(%q, %q) returned error: %s", tt.name, tt.zone, err)
+		}
+		if got != tt.want {
+			t.Errorf("Relative(%q, %q) = %q, want %q", tt.name, tt.zone, got, tt.want)
+		}
+	}
+}
+
+func TestRelativeOutsideZone(t *testing.T) {
+	for _, tt := range []struct{ name, zone string }{
+		{"example.com", "example.org"},
+		{"badexample.com", "example.com"},
+		{"example.com", "app.example.com"},
+	} {
+		if got, err := domainname.Relative(tt.name, tt.zone); err == nil {
+			t.Errorf("Relative(%q, %q) = %q, want error", tt.name, tt.zone, got)
+		}
+	}
+}
+
+func TestFQDN(t *testing.T) {
+	for _, in := range []string{"example.com", "example.com."} {
+		if got := domainname.FQDN(in); got != "example.com." {
+			t.Errorf("FQDN(%q) = %q, want %q", in, got, "example.com.")
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package fake
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"giautm.dev/googlesiteverification/internal/siteid"
+)
+
+// Check reports whether the verification token of a site is published.
+type Check func(ctx context.Context, site siteid.Site, token string) (bool, error)
+
+// Always reports every token as published.
+func Always() Check {
+	return func(context.Context, siteid.Site, string) (bool, error) { return true, nil }
+}
+
+// Never reports every token as not published.
+func Never() Check {
+	return func(context.Context, siteid.Site, string) (bool, error) { return false, nil }
+}
+
+// After reports a token as published from the (n+1)-th check of its site on,
+// as if the record took n checks to propagate.
+func After(n int) Check {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	return func(_ context.Context, site siteid.Site, _ string) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[site.ID()]++
+		return calls[site.ID()] > n, nil
+	}
+}
+
+// Not inverts c, for example to check that a token was removed from DNS.
+func Not(c Check) Check {
+	return func(ctx context.Context, site siteid.Site, token string) (bool, error) {
+		ok, err := c(ctx, site, token)
+		return !ok, err
+	}
+}
+
+// DNS looks the token up in the TXT records of the domain, using the DNS
+// server at resolver ("host:port"), or the system resolver when empty. Names
+// that do not exist are reported as not published.
+func DNS(resolver string) Check {
+	r := net.DefaultResolver
+	if resolver != "" {
+		r = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, resolver)
+			},
+		}
+	}
+	return func(ctx context.Context, site siteid.Site, token string) (bool, error) {
+		if !site.IsDomain() {
+			return false, nil
+		}
+		records, err := r.LookupTXT(ctx, site.Identifier)
+		if err != nil {
+			if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+				return false, nil
+			}
+			return false, err
+		}
+		for _, record := range records {
+			if record == token {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
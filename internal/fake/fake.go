@@ -0,0 +1,450 @@
+// Package fake implements an in-memory Site Verification API for tests.
+//
+// The server mimics the webResource methods of
+// https://developers.google.com/site-verification/v1, including the 400
+// errors returned while a verification token is not yet published in DNS or
+// is still published when unverifying.
+package fake
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/siteverification/v1"
+
+	"giautm.dev/googlesiteverification/internal/siteid"
+)
+
+// The API methods served by the fake, as used by Inject and Calls.
+const (
+	MethodGetToken = "getToken"
+	MethodInsert   = "insert"
+	MethodGet      = "get"
+	MethodList     = "list"
+	MethodUpdate   = "update"
+	MethodPatch    = "patch"
+	MethodDelete   = "delete"
+)
+
+// The messages of the 400 errors returned by the API while a token is not
+// yet published, and while it is still published.
+const (
+	MessageTokenNotFound = "The necessary verification token could not be found on your site."
+	MessageTokenExists   = "You cannot unverify your ownership of this site until your verification token (meta tag, HTML file, Google Analytics tracking code, Google Tag Manager container code, or DNS record) has been removed."
+)
+
+// DefaultOwner is the owner added to verifications by default.
+const DefaultOwner = "fake-owner@example.com"
+
+const basePath = "/siteVerification/v1"
+
+type fault struct {
+	code    int
+	message string
+	times   int
+}
+
+// Server is an in-memory Site Verification API. It is safe for concurrent
+// use and serves requests at both / and /siteVerification/v1/.
+type Server struct {
+	seed      string
+	owner     string
+	published Check
+	removed   Check
+
+	mu        sync.Mutex
+	resources map[string]*siteverification.SiteVerificationWebResourceResource
+	faults    map[string][]*fault
+	calls     map[string]int
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithTokenSeed changes the seed of the deterministic tokens, so that
+// different servers hand out different tokens for the same site.
+func WithTokenSeed(seed string) Option {
+	return func(s *Server) { s.seed = seed }
+}
+
+// WithOwner sets the owner added to new verifications.
+func WithOwner(email string) Option {
+	return func(s *Server) { s.owner = email }
+}
+
+// WithPublishedCheck sets the check that decides whether insert finds the
+// token. Insert fails with MessageTokenNotFound while it reports false. The
+// default is Always.
+func WithPublishedCheck(c Check) Option {
+	return func(s *Server) { s.published = c }
+}
+
+// WithRemovedCheck sets the check that decides whether delete finds the
+// token removed. Delete fails with MessageTokenExists while it reports false.
+// The default is Always.
+func WithRemovedCheck(c Check) Option {
+	return func(s *Server) { s.removed = c }
+}
+
+// New returns an empty Server.
+func New(opts ...Option) *Server {
+	s := &Server{
+		owner:     DefaultOwner,
+		published: Always(),
+		removed:   Always(),
+		resources: map[string]*siteverification.SiteVerificationWebResourceResource{},
+		faults:    map[string][]*fault{},
+		calls:     map[string]int{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Token returns the deterministic DNS TXT token of a domain.
+func (s *Server) Token(domain string) string {
+	return s.token(siteid.Site{Type: siteid.TypeDomain, Identifier: strings.ToLower(domain)})
+}
+
+func (s *Server) token(site siteid.Site) string {
+	sum := sha256.Sum256([]byte(s.seed + "\x00" + site.Type + "\x00" + site.Identifier))
+	return "google-site-verification=" + base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Inject makes the next times calls to method fail with code and message,
+// before the request is otherwise handled. A times of zero or less fails
+// every call.
+func (s *Server) Inject(method string, code int, message string, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[method] = append(s.faults[method], &fault{code: code, message: message, times: times})
+}
+
+// Calls returns the number of requests made to method.
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// Put stores a verification as if it was inserted, for tests that start
+// from an existing verification.
+func (s *Server) Put(domain string, owners ...string) {
+	site := siteid.Site{Type: siteid.TypeDomain, Identifier: strings.ToLower(domain)}
+	if len(owners) == 0 {
+		owners = []string{s.owner}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources[site.ID()] = newResource(site, owners)
+}
+
+// Verified reports whether a domain is verified.
+func (s *Server) Verified(domain string) bool {
+	site := siteid.Site{Type: siteid.TypeDomain, Identifier: strings.ToLower(domain)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.resources[site.ID()]
+	return ok
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimPrefix(r.URL.Path, basePath)
+	switch {
+	case p == "/token" && r.Method == http.MethodPost:
+		s.serve(w, r, MethodGetToken, s.getToken)
+	case p == "/webResource" && r.Method == http.MethodPost:
+		s.serve(w, r, MethodInsert, s.insert)
+	case p == "/webResource" && r.Method == http.MethodGet:
+		s.serve(w, r, MethodList, s.list)
+	case strings.HasPrefix(p, "/webResource/"):
+		id := strings.TrimPrefix(p, "/webResource/")
+		switch r.Method {
+		case http.MethodGet:
+			s.serve(w, r, MethodGet, func(r *http.Request) (interface{}, *fault) { return s.get(id) })
+		case http.MethodPut:
+			s.serve(w, r, MethodUpdate, func(r *http.Request) (interface{}, *fault) { return s.update(r, id, false) })
+		case http.MethodPatch:
+			s.serve(w, r, MethodPatch, func(r *http.Request) (interface{}, *fault) { return s.update(r, id, true) })
+		case http.MethodDelete:
+			s.serve(w, r, MethodDelete, func(r *http.Request) (interface{}, *fault) { return s.delete(r, id) })
+		default:
+			writeError(w, &fault{code: http.StatusMethodNotAllowed, message: "Method not allowed."})
+		}
+	default:
+		writeError(w, &fault{code: http.StatusNotFound, message: fmt.Sprintf("Not found: %s %s", r.Method, r.URL.Path)})
+	}
+}
+
+// serve counts the call, applies injected faults and writes the result of fn.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, method string, fn func(*http.Request) (interface{}, *fault)) {
+	s.mu.Lock()
+	s.calls[method]++
+	var injected *fault
+	if faults := s.faults[method]; len(faults) > 0 {
+		injected = faults[0]
+		if injected.times > 0 {
+			if injected.times--; injected.times == 0 {
+				s.faults[method] = faults[1:]
+			}
+		}
+	}
+	s.mu.Unlock()
+	if injected != nil {
+		writeError(w, injected)
+		return
+	}
+
+	result, f := fn(r)
+	if f != nil {
+		writeError(w, f)
+		return
+	}
+	if result == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func (s *Server) getToken(r *http.Request) (interface{}, *fault) {
+	var req siteverification.SiteVerificationWebResourceGettokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, badRequest("Invalid JSON payload received. %s", err)
+	}
+	if req.VerificationMethod == "" {
+		return nil, badRequest("Required parameter: verificationMethod")
+	}
+	if req.Site == nil {
+		return nil, badRequest("Required parameter: site")
+	}
+	site, f := parseSite(req.Site.Type, req.Site.Identifier)
+	if f != nil {
+		return nil, f
+	}
+	return &siteverification.SiteVerificationWebResourceGettokenResponse{
+		Method: req.VerificationMethod,
+		Token:  s.token(site),
+	}, nil
+}
+
+func (s *Server) insert(r *http.Request) (interface{}, *fault) {
+	if r.URL.Query().Get("verificationMethod") == "" {
+		return nil, badRequest("Required parameter: verificationMethod")
+	}
+	var req siteverification.SiteVerificationWebResourceResource
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, badRequest("Invalid JSON payload received. %s", err)
+	}
+	if req.Site == nil {
+		return nil, badRequest("Required parameter: site")
+	}
+	site, f := parseSite(req.Site.Type, req.Site.Identifier)
+	if f != nil {
+		return nil, f
+	}
+
+	published, err := s.published(r.Context(), site, s.token(site))
+	if err != nil {
+		return nil, &fault{code: http.StatusInternalServerError, message: fmt.Sprintf("Unable to check the verification token: %s", err)}
+	}
+	if !published {
+		return nil, badRequest(MessageTokenNotFound)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, ok := s.resources[site.ID()]
+	if !ok {
+		res = newResource(site, nil)
+		s.resources[site.ID()] = res
+	}
+	if !contains(res.Owners, s.owner) {
+		res.Owners = append(res.Owners, s.owner)
+	}
+	return clone(res), nil
+}
+
+func (s *Server) get(id string) (interface{}, *fault) {
+	site, f := parseID(id)
+	if f != nil {
+		return nil, f
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, ok := s.resources[site.ID()]
+	if !ok {
+		return nil, notFound(id)
+	}
+	return clone(res), nil
+}
+
+func (s *Server) list(r *http.Request) (interface{}, *fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.resources))
+	for id := range s.resources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	resp := &siteverification.SiteVerificationWebResourceListResponse{
+		Items: []*siteverification.SiteVerificationWebResourceResource{},
+	}
+	for _, id := range ids {
+		resp.Items = append(resp.Items, clone(s.resources[id]))
+	}
+	return resp, nil
+}
+
+func (s *Server) update(r *http.Request, id string, patch bool) (interface{}, *fault) {
+	site, f := parseID(id)
+	if f != nil {
+		return nil, f
+	}
+	var req siteverification.SiteVerificationWebResourceResource
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, badRequest("Invalid JSON payload received. %s", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, ok := s.resources[site.ID()]
+	if !ok {
+		return nil, notFound(id)
+	}
+	if req.Site != nil && (req.Site.Type != res.Site.Type || !strings.EqualFold(req.Site.Identifier, res.Site.Identifier)) {
+		return nil, badRequest("The site of a verification cannot be changed.")
+	}
+	if patch && req.Owners == nil {
+		return clone(res), nil
+	}
+	if len(req.Owners) == 0 {
+		return nil, badRequest("A verification must have at least one owner.")
+	}
+	res.Owners = append([]string(nil), req.Owners...)
+	return clone(res), nil
+}
+
+func (s *Server) delete(r *http.Request, id string) (interface{}, *fault) {
+	site, f := parseID(id)
+	if f != nil {
+		return nil, f
+	}
+	s.mu.Lock()
+	_, ok := s.resources[site.ID()]
+	s.mu.Unlock()
+	if !ok {
+		return nil, notFound(id)
+	}
+
+	removed, err := s.removed(r.Context(), site, s.token(site))
+	if err != nil {
+		return nil, &fault{code: http.StatusInternalServerError, message: fmt.Sprintf("Unable to check the verification token: %s", err)}
+	}
+	if !removed {
+		return nil, badRequest(MessageTokenExists)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.resources, site.ID())
+	return nil, nil
+}
+
+// parseID parses a web resource id, ignoring the case of domains.
+func parseID(id string) (siteid.Site, *fault) {
+	site, err := siteid.Parse(id)
+	if err != nil {
+		return siteid.Site{}, badRequest("Invalid id %q: %s", id, err)
+	}
+	return parseSite(site.Type, site.Identifier)
+}
+
+func parseSite(typ, identifier string) (siteid.Site, *fault) {
+	switch typ {
+	case siteid.TypeDomain:
+		identifier = strings.TrimSuffix(strings.ToLower(identifier), ".")
+	case siteid.TypeSite:
+	default:
+		return siteid.Site{}, badRequest("Invalid site type %q.", typ)
+	}
+	if identifier == "" {
+		return siteid.Site{}, badRequest("Required parameter: site.identifier")
+	}
+	return siteid.Site{Type: typ, Identifier: identifier}, nil
+}
+
+func newResource(site siteid.Site, owners []string) *siteverification.SiteVerificationWebResourceResource {
+	return &siteverification.SiteVerificationWebResourceResource{
+		Id:     url.QueryEscape(site.ID()),
+		Owners: owners,
+		Site: &siteverification.SiteVerificationWebResourceResourceSite{
+			Identifier: site.Identifier,
+			Type:       site.Type,
+		},
+	}
+}
+
+// clone copies res, so it can be encoded without holding the lock.
+func clone(res *siteverification.SiteVerificationWebResourceResource) *siteverification.SiteVerificationWebResourceResource {
+	site := *res.Site
+	return &siteverification.SiteVerificationWebResourceResource{
+		Id:     res.Id,
+		Owners: append([]string(nil), res.Owners...),
+		Site:   &site,
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func badRequest(format string, args ...interface{}) *fault {
+	return &fault{code: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(id string) *fault {
+	return &fault{code: http.StatusNotFound, message: fmt.Sprintf("Site %s not found.", id)}
+}
+
+// writeError writes f in the JSON error format of Google APIs.
+func writeError(w http.ResponseWriter, f *fault) {
+	reason := "backendError"
+	switch f.code {
+	case http.StatusBadRequest:
+		reason = "badRequest"
+	case http.StatusForbidden:
+		reason = "forbidden"
+	case http.StatusNotFound:
+		reason = "notFound"
+	case http.StatusTooManyRequests:
+		reason = "rateLimitExceeded"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.code)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    f.code,
+			"message": f.message,
+			"errors": []map[string]string{{
+				"domain":  "global",
+				"reason":  reason,
+				"message": f.message,
+			}},
+		},
+	})
+}
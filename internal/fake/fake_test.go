@@ -0,0 +1,250 @@
+package fake_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/siteverification/v1"
+
+	"giautm.dev/googlesiteverification/internal/fake"
+	"giautm.dev/googlesiteverification/internal/siteid"
+)
+
+func testService(t *testing.T, s *fake.Server) *siteverification.Service {
+	t.Helper()
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	svc, err := siteverification.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithEndpoint(srv.URL+"/siteVerification/v1/"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+func getToken(svc *siteverification.Service, domain string) (string, error) {
+	resp, err := svc.WebResource.GetToken(&siteverification.SiteVerificationWebResourceGettokenRequest{
+		Site: &siteverification.SiteVerificationWebResourceGettokenRequestSite{
+			Identifier: domain,
+			Type:       siteid.TypeDomain,
+		},
+		VerificationMethod: "DNS_TXT",
+	}).Do()
+	if err != nil {
+		return "", err
+	}
+	return resp.Token, nil
+}
+
+func insert(svc *siteverification.Service, domain string) (*siteverification.SiteVerificationWebResourceResource, error) {
+	return svc.WebResource.Insert("DNS_TXT", &siteverification.SiteVerificationWebResourceResource{
+		Site: &siteverification.SiteVerificationWebResourceResourceSite{
+			Identifier: domain,
+			Type:       siteid.TypeDomain,
+		},
+	}).Do()
+}
+
+func wantAPIError(t *testing.T, err error, code int, message string) {
+	t.Helper()
+
+	var apierr *googleapi.Error
+	if !errors.As(err, &apierr) {
+		t.Fatalf("error = %v, want a googleapi.Error", err)
+	}
+	if apierr.Code != code || apierr.Message != message {
+		t.Errorf("error = %d %q, want %d %q", apierr.Code, apierr.Message, code, message)
+	}
+}
+
+func TestToken(t *testing.T) {
+	s := fake.New()
+	svc := testService(t, s)
+
+	token, err := getToken(svc, "Example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != s.Token("example.com") {
+		t.Errorf("getToken() = %q, want %q", token, s.Token("example.com"))
+	}
+	if again, _ := getToken(testService(t, fake.New()), "example.com"); again != token {
+		t.Errorf("getToken() = %q on another server, want the deterministic %q", again, token)
+	}
+	if other := fake.New(fake.WithTokenSeed("other")).Token("example.com"); other == token {
+		t.Errorf("Token() = %q with another seed, want a different token", other)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	s := fake.New(fake.WithOwner("owner@example.com"))
+	svc := testService(t, s)
+
+	res, err := insert(svc, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Id != "dns%3A%2F%2Fexample.com" || len(res.Owners) != 1 || res.Owners[0] != "owner@example.com" {
+		t.Errorf("insert() = %+v", res)
+	}
+	if !s.Verified("example.com") {
+		t.Error("Verified() = false after insert")
+	}
+
+	if _, err := svc.WebResource.Get("dns://example.com").Do(); err != nil {
+		t.Errorf("get() returned error: %s", err)
+	}
+	list, err := svc.WebResource.List().Do()
+	if err != nil || len(list.Items) != 1 {
+		t.Errorf("list() = %+v, %v", list, err)
+	}
+
+	res.Owners = append(res.Owners, "other@example.com")
+	res, err = svc.WebResource.Update(res.Id, res).Do()
+	if err != nil || len(res.Owners) != 2 {
+		t.Errorf("update() = %+v, %v", res, err)
+	}
+	res, err = svc.WebResource.Patch("dns://example.com", &siteverification.SiteVerificationWebResourceResource{
+		Owners: []string{"other@example.com"},
+	}).Do()
+	if err != nil || len(res.Owners) != 1 || res.Owners[0] != "other@example.com" {
+		t.Errorf("patch() = %+v, %v", res, err)
+	}
+
+	if err := svc.WebResource.Delete("dns://example.com").Do(); err != nil {
+		t.Fatalf("delete() returned error: %s", err)
+	}
+	_, err = svc.WebResource.Get("dns://example.com").Do()
+	wantAPIError(t, err, http.StatusNotFound, "Site dns://example.com not found.")
+	if s.Calls(fake.MethodGet) != 2 {
+		t.Errorf("Calls(get) = %d, want 2", s.Calls(fake.MethodGet))
+	}
+}
+
+func TestChecks(t *testing.T) {
+	t.Run("never published", func(t *testing.T) {
+		svc := testService(t, fake.New(fake.WithPublishedCheck(fake.Never())))
+		_, err := insert(svc, "example.com")
+		wantAPIError(t, err, http.StatusBadRequest, fake.MessageTokenNotFound)
+	})
+
+	t.Run("published after", func(t *testing.T) {
+		s := fake.New(fake.WithPublishedCheck(fake.After(2)))
+		svc := testService(t, s)
+		for i := 0; i < 2; i++ {
+			_, err := insert(svc, "example.com")
+			wantAPIError(t, err, http.StatusBadRequest, fake.MessageTokenNotFound)
+		}
+		if _, err := insert(svc, "example.com"); err != nil {
+			t.Fatalf("insert() returned error after 2 calls: %s", err)
+		}
+		if s.Calls(fake.MethodInsert) != 3 {
+			t.Errorf("Calls(insert) = %d, want 3", s.Calls(fake.MethodInsert))
+		}
+	})
+
+	t.Run("never removed", func(t *testing.T) {
+		s := fake.New(fake.WithRemovedCheck(fake.Never()))
+		s.Put("example.com")
+		err := testService(t, s).WebResource.Delete("dns://example.com").Do()
+		wantAPIError(t, err, http.StatusBadRequest, fake.MessageTokenExists)
+		if !s.Verified("example.com") {
+			t.Error("Verified() = false after a failed delete")
+		}
+	})
+}
+
+func TestInject(t *testing.T) {
+	s := fake.New()
+	svc := testService(t, s)
+	s.Inject(fake.MethodInsert, http.StatusBadRequest, fake.MessageTokenNotFound, 1)
+	s.Inject(fake.MethodInsert, http.StatusTooManyRequests, "Quota exceeded.", 1)
+
+	_, err := insert(svc, "example.com")
+	wantAPIError(t, err, http.StatusBadRequest, fake.MessageTokenNotFound)
+	_, err = insert(svc, "example.com")
+	wantAPIError(t, err, http.StatusTooManyRequests, "Quota exceeded.")
+	if _, err := insert(svc, "example.com"); err != nil {
+		t.Fatalf("insert() returned error after the injected faults: %s", err)
+	}
+}
+
+// testDNSServer answers TXT queries for example.com with txt.
+func testDNSServer(t *testing.T, txt string) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var p dnsmessage.Parser
+			h, err := p.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			q, err := p.Question()
+			if err != nil {
+				continue
+			}
+			b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true})
+			_ = b.StartQuestions()
+			_ = b.Question(q)
+			_ = b.StartAnswers()
+			if q.Type == dnsmessage.TypeTXT && q.Name.String() == "example.com." {
+				_ = b.TXTResource(dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60},
+					dnsmessage.TXTResource{TXT: []string{txt}})
+			}
+			msg, err := b.Finish()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(msg, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNS(t *testing.T) {
+	s := fake.New()
+	resolver := testDNSServer(t, s.Token("example.com"))
+	check := fake.DNS(resolver)
+
+	for _, tt := range []struct {
+		domain string
+		want   bool
+	}{
+		{domain: "example.com", want: true},
+		{domain: "example.org", want: false},
+	} {
+		site := siteid.Site{Type: siteid.TypeDomain, Identifier: tt.domain}
+		got, err := check(context.Background(), site, s.Token(tt.domain))
+		if err != nil {
+			t.Fatalf("DNS(%s) returned error: %s", tt.domain, err)
+		}
+		if got != tt.want {
+			t.Errorf("DNS(%s) = %t, want %t", tt.domain, got, tt.want)
+		}
+		if removed, _ := fake.Not(check)(context.Background(), site, s.Token(tt.domain)); removed == tt.want {
+			t.Errorf("Not(DNS(%s)) = %t, want %t", tt.domain, removed, !tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"giautm.dev/googlesiteverification/internal/cassette"
+	"giautm.dev/googlesiteverification/internal/fake"
+)
+
+func TestConfigureTransport(t *testing.T) {
+	t.Setenv(endpointEnvVar, "")
+	s := fake.New()
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		s.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	configure := func(c *cassette.Cassette) *providerClient {
+		t.Helper()
+		resp := &provider.ConfigureResponse{}
+		NewWithTransport("test", c.Transport, time.Millisecond)().Configure(context.Background(), provider.ConfigureRequest{
+			Config: testProviderConfig(t, map[string]tftypes.Value{
+				"access_token": tftypes.NewValue(tftypes.String, "recorded-token"),
+				"endpoint":     tftypes.NewValue(tftypes.String, srv.URL),
+			}),
+		}, resp)
+		if resp.Diagnostics.HasError() {
+			t.Fatalf("Configure() diagnostics = %v", resp.Diagnostics)
+		}
+		data := resp.ResourceData.(*providerClient)
+		if data.retryInterval != time.Millisecond {
+			t.Errorf("retryInterval = %s, want 1ms", data.retryInterval)
+		}
+		return data
+	}
+
+	rec, err := cassette.New(path, cassette.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := configure(rec).GetToken(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer recorded-token" {
+		t.Errorf("Authorization = %q, want the access token", auth)
+	}
+	if len(rec.Interactions()) != 1 {
+		t.Fatalf("Interactions() = %+v, want the getToken call", rec.Interactions())
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.Close()
+	play, err := cassette.New(path, cassette.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := configure(play).GetToken(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed == token || replayed != "google-site-verification=scrubbed-token-1" {
+		t.Errorf("getToken() = %q on replay, want the scrubbed token", replayed)
+	}
+}
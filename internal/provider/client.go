@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"google.golang.org/api/siteverification/v1"
+
+	"giautm.dev/googlesiteverification/verifier"
+)
+
+// apiClient is shared with resources and data sources by Configure. It is
+// the client of the API, along with the configuration shared by every call.
+type apiClient interface {
+	verifier.API
+	// Capabilities returns the API methods permitted by the configured scopes.
+	Capabilities() capabilities
+	// RetryInterval returns how long to wait between verification attempts.
+	RetryInterval() time.Duration
+}
+
+// providerClient is the apiClient configured by the provider.
+type providerClient struct {
+	verifier.API
+	caps          capabilities
+	retryInterval time.Duration
+}
+
+var _ apiClient = &providerClient{}
+
+func (c *providerClient) Capabilities() capabilities {
+	return c.caps
+}
+
+func (c *providerClient) RetryInterval() time.Duration {
+	return c.retryInterval
+}
+
+// ClientConfig configures NewService like the provider attributes of the
+// same names, and is validated the same way. Empty fields are null, so that
+// the credentials and the endpoint fall back to the environment variables
+// read by the provider.
+type ClientConfig struct {
+	Credentials                        string
+	AccessToken                        string
+	ImpersonateServiceAccount          string
+	ImpersonateServiceAccountDelegates []string
+	IAMCredentialsEndpoint             string
+	Subject                            string
+	Scopes                             []string
+	UserProjectOverride                bool
+	BillingProject                     string
+	RequestReason                      string
+	Endpoint                           string
+	Emulator                           bool
+}
+
+// model returns the provider configuration equivalent to c.
+func (c ClientConfig) model() GoogleSiteVerificationProviderModel {
+	return GoogleSiteVerificationProviderModel{
+		Credentials:                        nullableString(c.Credentials),
+		AccessToken:                        nullableString(c.AccessToken),
+		ImpersonateServiceAccount:          nullableString(c.ImpersonateServiceAccount),
+		ImpersonateServiceAccountDelegates: nullableList(c.ImpersonateServiceAccountDelegates),
+		IAMCredentialsEndpoint:             nullableString(c.IAMCredentialsEndpoint),
+		Subject:                            nullableString(c.Subject),
+		Scopes:                             nullableList(c.Scopes),
+		UserProjectOverride:                nullableBool(c.UserProjectOverride),
+		BillingProject:                     nullableString(c.BillingProject),
+		RequestReason:                      nullableString(c.RequestReason),
+		Endpoint:                           nullableString(c.Endpoint),
+		Emulator:                           nullableBool(c.Emulator),
+	}
+}
+
+// NewService returns a Site Verification API client resolved from config
+// the same way as the provider configuration, for use outside Terraform.
+func NewService(ctx context.Context, config ClientConfig) (*siteverification.Service, error) {
+	p := &GoogleSiteVerificationProvider{}
+	data := config.model()
+	if diags := p.validate(ctx, data); diags.HasError() {
+		return nil, diagnosticsError(diags)
+	}
+	srv, _, diags := p.newService(ctx, data)
+	if diags.HasError() {
+		return nil, diagnosticsError(diags)
+	}
+	return srv, nil
+}
+
+// validate runs the attribute and configuration validators of the provider
+// schema on data, which Terraform runs before Configure.
+func (p *GoogleSiteVerificationProvider) validate(ctx context.Context, data GoogleSiteVerificationProviderModel) diag.Diagnostics {
+	schema, diags := p.GetSchema(ctx)
+	if diags.HasError() {
+		return diags
+	}
+	state := tfsdk.State{Schema: schema, Raw: tftypes.NewValue(schema.Type().TerraformType(ctx), nil)}
+	diags.Append(state.Set(ctx, &data)...)
+	if diags.HasError() {
+		return diags
+	}
+	config := tfsdk.Config{Schema: schema, Raw: state.Raw}
+
+	names := make([]string, 0, len(schema.Attributes))
+	for name := range schema.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var value attr.Value
+		diags.Append(config.GetAttribute(ctx, path.Root(name), &value)...)
+		if diags.HasError() {
+			return diags
+		}
+		for _, v := range schema.Attributes[name].Validators {
+			resp := &tfsdk.ValidateAttributeResponse{}
+			v.Validate(ctx, tfsdk.ValidateAttributeRequest{
+				AttributePath:   path.Root(name),
+				AttributeConfig: value,
+				Config:          config,
+			}, resp)
+			diags.Append(resp.Diagnostics...)
+		}
+	}
+	for _, v := range p.ConfigValidators(ctx) {
+		resp := &provider.ValidateConfigResponse{}
+		v.ValidateProvider(ctx, provider.ValidateConfigRequest{Config: config}, resp)
+		diags.Append(resp.Diagnostics...)
+	}
+	return diags
+}
+
+func nullableString(s string) types.String {
+	if s == "" {
+		return types.String{Null: true}
+	}
+	return types.String{Value: s}
+}
+
+func nullableBool(b bool) types.Bool {
+	if !b {
+		return types.Bool{Null: true}
+	}
+	return types.Bool{Value: true}
+}
+
+func nullableList(elems []string) types.List {
+	if elems == nil {
+		return types.List{ElemType: types.StringType, Null: true}
+	}
+	values := make([]attr.Value, len(elems))
+	for i, elem := range elems {
+		values[i] = types.String{Value: elem}
+	}
+	return types.List{ElemType: types.StringType, Elems: values}
+}
+
+// diagnosticsError returns the errors in diags as a single error.
+func diagnosticsError(diags diag.Diagnostics) error {
+	var msgs []string
+	for _, d := range diags.Errors() {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", d.Summary(), d.Detail()))
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
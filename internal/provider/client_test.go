@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"giautm.dev/googlesiteverification/internal/fake"
+	"giautm.dev/googlesiteverification/verifier"
+)
+
+func TestNewService(t *testing.T) {
+	ctx := context.Background()
+	s := fake.New()
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		s.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	emulator := ClientConfig{Emulator: true, Endpoint: srv.URL + "/siteVerification/v1/"}
+	t.Setenv(endpointEnvVar, "")
+
+	for name, tt := range map[string]struct {
+		config     ClientConfig
+		env        string
+		wantError  string
+		wantHeader http.Header
+	}{
+		"emulator":         {config: emulator},
+		"endpoint env":     {config: ClientConfig{Emulator: true}, env: srv.URL + "/siteVerification/v1"},
+		"missing endpoint": {config: ClientConfig{Emulator: true}, wantError: "Missing endpoint"},
+		"invalid endpoint": {config: ClientConfig{Emulator: true, Endpoint: "localhost:8080"}, wantError: "Invalid endpoint"},
+		"conflict":         {config: ClientConfig{AccessToken: "token", Credentials: "{}"}, wantError: "Conflicting Attributes"},
+		"subject conflict": {config: ClientConfig{Subject: "user@example.com", ImpersonateServiceAccount: "sa@example.iam.gserviceaccount.com"}, wantError: "Conflicting Attributes"},
+		"invalid scope":    {config: ClientConfig{Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"}}, wantError: "Invalid Scope"},
+		"delegates":        {config: ClientConfig{ImpersonateServiceAccountDelegates: []string{"sa@example.iam.gserviceaccount.com"}}, wantError: "Missing impersonate_service_account"},
+		"billing project":  {config: ClientConfig{UserProjectOverride: true}, wantError: "Missing billing_project"},
+		"headers": {config: ClientConfig{
+			Emulator:            true,
+			Endpoint:            emulator.Endpoint,
+			UserProjectOverride: true,
+			BillingProject:      "billing",
+			RequestReason:       "audit",
+		}, wantHeader: http.Header{"X-Goog-User-Project": {"billing"}, "X-Goog-Request-Reason": {"audit"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(endpointEnvVar, tt.env)
+			got, err := NewService(ctx, tt.config)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Errorf("NewService() returned error %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewService() returned error: %s", err)
+			}
+			token, err := verifier.NewAPI(got).GetToken(ctx, "example.com")
+			if err != nil || token != s.Token("example.com") {
+				t.Errorf("GetToken() = %q, %v, want %q", token, err, s.Token("example.com"))
+			}
+			for name := range tt.wantHeader {
+				if got, want := header.Get(name), tt.wantHeader.Get(name); got != want {
+					t.Errorf("%s header = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestClientConfigFields checks that ClientConfig has a field for every
+// provider attribute.
+func TestClientConfigFields(t *testing.T) {
+	model := reflect.TypeOf(GoogleSiteVerificationProviderModel{})
+	config := reflect.TypeOf(ClientConfig{})
+	for i := 0; i < model.NumField(); i++ {
+		name := model.Field(i).Name
+		if _, ok := config.FieldByName(name); !ok {
+			t.Errorf("ClientConfig has no %s field for the %s attribute", name, model.Field(i).Tag.Get("tfsdk"))
+		}
+	}
+	if model.NumField() != config.NumField() {
+		t.Errorf("ClientConfig has %d fields, want %d", config.NumField(), model.NumField())
+	}
+}
@@ -0,0 +1,397 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/iamcredentials/v1"
+	"google.golang.org/api/option"
+)
+
+// credentialsSource is where the provider found its credentials.
+type credentialsSource struct {
+	// name is the attribute or environment variable the value came from.
+	name string
+	// value is an access token, or the path to or contents of a key file.
+	value string
+	// accessToken reports whether value is an access token.
+	accessToken bool
+	// attribute reports whether value came from the provider configuration.
+	attribute bool
+}
+
+// credentialsEnvVars are the environment variables holding the path to or
+// contents of a key file, in the precedence order of the Google provider.
+var credentialsEnvVars = []string{
+	"GOOGLE_CREDENTIALS",
+	"GOOGLE_CLOUD_KEYFILE_JSON",
+	"GCLOUD_KEYFILE_JSON",
+	"GOOGLE_APPLICATION_CREDENTIALS",
+}
+
+// credentialsSource returns the credentials with the highest precedence:
+// the access_token and credentials attributes, then GOOGLE_OAUTH_ACCESS_TOKEN,
+// then credentialsEnvVars. It returns false when the application default
+// credentials should be used.
+func (m *GoogleSiteVerificationProviderModel) credentialsSource(getenv func(string) string) (credentialsSource, bool) {
+	if token := m.AccessToken.Value; token != "" {
+		return credentialsSource{name: "access_token", value: token, accessToken: true, attribute: true}, true
+	}
+	if creds := m.Credentials.Value; creds != "" {
+		return credentialsSource{name: "credentials", value: creds, attribute: true}, true
+	}
+	if token := getenv(accessTokenEnvVar); token != "" {
+		return credentialsSource{name: accessTokenEnvVar, value: token, accessToken: true}, true
+	}
+	for _, name := range credentialsEnvVars {
+		if creds := getenv(name); creds != "" {
+			return credentialsSource{name: name, value: creds}, true
+		}
+	}
+	return credentialsSource{}, false
+}
+
+// clientOptions returns the options that authenticate the API clients built
+// by the provider with scopes.
+func (m *GoogleSiteVerificationProviderModel) clientOptions(ctx context.Context, scopes []string) ([]option.ClientOption, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	var opts []option.ClientOption
+	src, ok := m.credentialsSource(os.Getenv)
+	subject := m.Subject.Value
+	if subject != "" && (!ok || src.accessToken) {
+		from := "the application default credentials"
+		if ok {
+			from = "the access token from " + src.name
+		}
+		diags.AddAttributeError(path.Root("subject"),
+			"Domain-wide delegation not supported",
+			fmt.Sprintf("Domain-wide delegation requires a service account key, but the provider is using %s. Set credentials to a service account key file.", from),
+		)
+		return nil, diags
+	}
+	switch {
+	case !ok:
+		tflog.Debug(ctx, "Using application default credentials")
+	case src.accessToken:
+		tflog.Debug(ctx, "Using access token", map[string]interface{}{
+			"source": src.name,
+		})
+		opts = append(opts, accessTokenOption(src.value, src.name, m.requestOptions()))
+	default:
+		credScopes := scopes
+		if m.ImpersonateServiceAccount.Value != "" {
+			credScopes = []string{iamcredentials.CloudPlatformScope}
+		}
+		optCreds, d := src.option(ctx, context.Background(), credScopes, subject)
+		diags.Append(d...)
+		if diags.HasError() {
+			return nil, diags
+		}
+		opts = append(opts, optCreds)
+	}
+	if !ok || !src.accessToken {
+		opts = append(opts, m.requestOptions().clientOptions()...)
+	}
+
+	if target := m.ImpersonateServiceAccount.Value; target != "" {
+		if endpoint := m.IAMCredentialsEndpoint.Value; endpoint != "" {
+			opts = append(opts, option.WithEndpoint(endpoint))
+		}
+		var delegates []string
+		diags.Append(m.ImpersonateServiceAccountDelegates.ElementsAs(ctx, &delegates, true)...)
+		if diags.HasError() {
+			return nil, diags
+		}
+		ts, err := impersonatedTokenSource(context.Background(), target, delegates, scopes, opts...)
+		if err != nil {
+			diags.AddAttributeError(path.Root("impersonate_service_account"),
+				"Unable to impersonate service account",
+				fmt.Sprintf("Unable to create IAM Credentials client for %q: %s", target, err),
+			)
+			return nil, diags
+		}
+		tflog.Debug(ctx, "Impersonating service account", map[string]interface{}{
+			"service_account": target,
+			"delegates":       delegates,
+		})
+		opts := append([]option.ClientOption{option.WithTokenSource(ts)}, m.requestOptions().clientOptions()...)
+		return opts, diags
+	}
+	return append(opts, option.WithScopes(scopes...)), diags
+}
+
+// requestOptions are applied to every request made by the provider.
+type requestOptions struct {
+	// quotaProject is the project billed for quota.
+	quotaProject string
+	// reason is sent as the X-Goog-Request-Reason header for audit logs.
+	reason string
+}
+
+// requestOptions returns the billing project, when user_project_override is
+// set, and the request reason.
+func (m *GoogleSiteVerificationProviderModel) requestOptions() requestOptions {
+	var o requestOptions
+	if m.UserProjectOverride.Value {
+		o.quotaProject = m.BillingProject.Value
+	}
+	o.reason = m.RequestReason.Value
+	return o
+}
+
+func (o requestOptions) clientOptions() []option.ClientOption {
+	var opts []option.ClientOption
+	if o.quotaProject != "" {
+		opts = append(opts, option.WithQuotaProject(o.quotaProject))
+	}
+	if o.reason != "" {
+		opts = append(opts, option.WithRequestReason(o.reason))
+	}
+	return opts
+}
+
+// setHeaders sets the headers that option.WithQuotaProject and
+// option.WithRequestReason would, for clients built with a custom
+// http.Client that ignores those options.
+func (o requestOptions) setHeaders(h http.Header) {
+	if o.quotaProject != "" {
+		h.Set("X-Goog-User-Project", o.quotaProject)
+	}
+	if o.reason != "" {
+		h.Set("X-Goog-Request-Reason", o.reason)
+	}
+}
+
+// option returns the client option for a key file, or its contents. Workload
+// Identity Federation configurations are validated and loaded explicitly, and
+// exchange tokens with tokenCtx since they outlive the Configure request.
+// When subject is set, the service account key signs tokens on behalf of that
+// user through domain-wide delegation.
+func (src credentialsSource) option(ctx, tokenCtx context.Context, scopes []string, subject string) (option.ClientOption, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	data := []byte(src.value)
+	isFile := !json.Valid(data)
+	if isFile {
+		var err error
+		data, err = os.ReadFile(src.value)
+		if err != nil {
+			src.addError(&diags, "Invalid credentials",
+				fmt.Sprintf("The credentials file %q could not be found.", src.value))
+			return nil, diags
+		}
+	}
+
+	var header struct {
+		Type string `json:"type"`
+	}
+	err := json.Unmarshal(data, &header)
+	if subject != "" {
+		return src.delegatedOption(ctx, tokenCtx, data, header.Type, scopes, subject)
+	}
+	if err != nil || header.Type != externalAccountType {
+		tflog.Debug(ctx, "Using credentials", map[string]interface{}{
+			"source": src.name,
+			"type":   header.Type,
+		})
+		if isFile {
+			return option.WithCredentialsFile(src.value), diags
+		}
+		return option.WithCredentialsJSON(data), diags
+	}
+
+	var cfg externalAccountConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		src.addError(&diags, "Invalid external_account credentials",
+			fmt.Sprintf("Unable to parse the Workload Identity Federation configuration: %s", err))
+		return nil, diags
+	}
+	problems, warnings := cfg.validate()
+	if len(warnings) > 0 {
+		diags.AddWarning("Incomplete external_account credentials",
+			fmt.Sprintf("The Workload Identity Federation configuration from %s may not work:\n\n- %s",
+				src.name, strings.Join(warnings, "\n- ")))
+	}
+	if len(problems) > 0 {
+		src.addError(&diags, "Invalid external_account credentials",
+			fmt.Sprintf("The Workload Identity Federation configuration has the following problems:\n\n- %s",
+				strings.Join(problems, "\n- ")))
+		return nil, diags
+	}
+
+	creds, err := google.CredentialsFromJSON(tokenCtx, data, scopes...)
+	if err != nil {
+		src.addError(&diags, "Invalid external_account credentials",
+			fmt.Sprintf("Unable to load the Workload Identity Federation configuration: %s", err))
+		return nil, diags
+	}
+	tflog.Debug(ctx, "Using external account credentials", map[string]interface{}{
+		"source":   src.name,
+		"audience": cfg.Audience,
+	})
+	return option.WithCredentials(creds), diags
+}
+
+// delegatedOption returns the client option for a service account key acting
+// as subject. Only service account keys can sign the JWT assertion that
+// domain-wide delegation relies on.
+func (src credentialsSource) delegatedOption(ctx, tokenCtx context.Context, data []byte, keyType string, scopes []string, subject string) (option.ClientOption, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if keyType != serviceAccountType {
+		diags.AddAttributeError(path.Root("subject"),
+			"Domain-wide delegation not supported",
+			fmt.Sprintf("Domain-wide delegation requires a %q key, but the credentials from %s are of type %q.", serviceAccountType, src.name, keyType),
+		)
+		return nil, diags
+	}
+	conf, err := google.JWTConfigFromJSON(data, scopes...)
+	if err != nil {
+		src.addError(&diags, "Invalid credentials",
+			fmt.Sprintf("Unable to parse the service account key: %s", err))
+		return nil, diags
+	}
+	conf.Subject = subject
+	tflog.Debug(ctx, "Using domain-wide delegation", map[string]interface{}{
+		"source":          src.name,
+		"service_account": conf.Email,
+		"subject":         subject,
+	})
+	return option.WithTokenSource(conf.TokenSource(tokenCtx)), diags
+}
+
+// addError reports an error against the attribute the credentials came
+// from, or names the environment variable they were read from.
+func (src credentialsSource) addError(diags *diag.Diagnostics, summary, detail string) {
+	if src.attribute {
+		diags.AddAttributeError(path.Root(src.name), summary, detail)
+		return
+	}
+	diags.AddError(summary, fmt.Sprintf("%s\n\nThe credentials were read from the %s environment variable.", detail, src.name))
+}
+
+const serviceAccountType = "service_account"
+
+const accessTokenEnvVar = "GOOGLE_OAUTH_ACCESS_TOKEN"
+
+// accessTokenOption authenticates requests with a static OAuth 2.0 access
+// token. Since the token cannot be refreshed, a rejected token is reported
+// as expired together with where it came from.
+func accessTokenOption(token, source string, reqOpts requestOptions) option.ClientOption {
+	return option.WithHTTPClient(&http.Client{
+		Transport: &accessTokenTransport{
+			source:  source,
+			reqOpts: reqOpts,
+			base: &oauth2.Transport{
+				Source: oauth2.StaticTokenSource(&oauth2.Token{
+					AccessToken: token,
+					TokenType:   "Bearer",
+				}),
+			},
+		},
+	})
+}
+
+// accessTokenTransport rewrites 401 responses into an error that explains
+// that the static access token is invalid or has expired. It also sets the
+// request headers the client ignores when given a custom http.Client.
+type accessTokenTransport struct {
+	source  string
+	reqOpts requestOptions
+	base    http.RoundTripper
+}
+
+func (t *accessTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	t.reqOpts.setHeaders(req.Header)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	_ = resp.Body.Close()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    http.StatusUnauthorized,
+			"message": fmt.Sprintf("The access token from %s is invalid or has expired. Access tokens cannot be refreshed by the provider, request a new token and try again.", t.source),
+			"status":  "UNAUTHENTICATED",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// iamTokenSource mints short-lived access tokens for a service account
+// through the IAM Credentials API.
+type iamTokenSource struct {
+	ctx       context.Context
+	srv       *iamcredentials.Service
+	name      string
+	delegates []string
+	scopes    []string
+}
+
+// impersonatedTokenSource returns a token source for targetPrincipal with
+// scopes, using the credentials in opts, or the application default
+// credentials, to call the IAM Credentials API.
+func impersonatedTokenSource(ctx context.Context, targetPrincipal string, delegates, scopes []string, opts ...option.ClientOption) (oauth2.TokenSource, error) {
+	opts = append([]option.ClientOption{option.WithScopes(iamcredentials.CloudPlatformScope)}, opts...)
+	srv, err := iamcredentials.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ts := &iamTokenSource{
+		ctx:    ctx,
+		srv:    srv,
+		name:   serviceAccountName(targetPrincipal),
+		scopes: scopes,
+	}
+	for _, delegate := range delegates {
+		ts.delegates = append(ts.delegates, serviceAccountName(delegate))
+	}
+	return oauth2.ReuseTokenSource(nil, ts), nil
+}
+
+func (s *iamTokenSource) Token() (*oauth2.Token, error) {
+	resp, err := s.srv.Projects.ServiceAccounts.
+		GenerateAccessToken(s.name, &iamcredentials.GenerateAccessTokenRequest{
+			Delegates: s.delegates,
+			Scope:     s.scopes,
+		}).
+		Context(s.ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate access token for %s: %w", s.name, err)
+	}
+	expiry, err := time.Parse(time.RFC3339, resp.ExpireTime)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse access token expiry %q: %w", resp.ExpireTime, err)
+	}
+	return &oauth2.Token{
+		AccessToken: resp.AccessToken,
+		TokenType:   "Bearer",
+		Expiry:      expiry,
+	}, nil
+}
+
+func serviceAccountName(email string) string {
+	return "projects/-/serviceAccounts/" + email
+}
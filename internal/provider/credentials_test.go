@@ -0,0 +1,409 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/siteverification/v1"
+)
+
+func TestImpersonatedTokenSource(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if want := "/v1/projects/-/serviceAccounts/target@example.iam.gserviceaccount.com:generateAccessToken"; r.URL.Path != want {
+			t.Errorf("path = %q, want %q", r.URL.Path, want)
+		}
+		if got, want := r.Header.Get("Authorization"), "Bearer base-token"; got != want {
+			t.Errorf("Authorization = %q, want %q", got, want)
+		}
+		var body struct {
+			Delegates []string `json:"delegates"`
+			Scope     []string `json:"scope"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Delegates) != 1 || body.Delegates[0] != "projects/-/serviceAccounts/delegate@example.iam.gserviceaccount.com" {
+			t.Errorf("delegates = %v", body.Delegates)
+		}
+		if len(body.Scope) != 1 || body.Scope[0] != "https://www.googleapis.com/auth/siteverification" {
+			t.Errorf("scope = %v", body.Scope)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"accessToken": "impersonated-token",
+			"expireTime":  expiry.Format(time.RFC3339),
+		})
+	}))
+	defer srv.Close()
+
+	ts, err := impersonatedTokenSource(context.Background(),
+		"target@example.iam.gserviceaccount.com",
+		[]string{"delegate@example.iam.gserviceaccount.com"},
+		[]string{siteverification.SiteverificationScope},
+		option.WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "base-token"})),
+		option.WithEndpoint(srv.URL),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		tok, err := ts.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.AccessToken != "impersonated-token" || !tok.Expiry.Equal(expiry) {
+			t.Errorf("Token() = %+v", tok)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("IAM Credentials API called %d times, want the token to be reused", calls)
+	}
+}
+
+func TestImpersonatedTokenSourceError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":{"code":403,"message":"Permission 'iam.serviceAccounts.getAccessToken' denied","status":"PERMISSION_DENIED"}}`))
+	}))
+	defer srv.Close()
+
+	ts, err := impersonatedTokenSource(context.Background(),
+		"target@example.iam.gserviceaccount.com", nil, nil,
+		option.WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "base-token"})),
+		option.WithEndpoint(srv.URL),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Token(); err == nil {
+		t.Error("Token() succeeded, want permission error")
+	}
+}
+
+func TestAccessTokenOption(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer valid-token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"code":401,"message":"Request had invalid authentication credentials.","status":"UNAUTHENTICATED"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"dns%3A%2F%2Fexample.com","site":{"identifier":"example.com","type":"INET_DOMAIN"}}`))
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		token   string
+		wantErr string
+	}{
+		{token: "valid-token"},
+		{token: "expired-token", wantErr: "The access token from GOOGLE_OAUTH_ACCESS_TOKEN is invalid or has expired."},
+	} {
+		t.Run(tt.token, func(t *testing.T) {
+			svc, err := siteverification.NewService(context.Background(),
+				accessTokenOption(tt.token, accessTokenEnvVar, requestOptions{}),
+				option.WithEndpoint(srv.URL),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = svc.WebResource.Get("dns://example.com").Do()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Get() returned error: %s", err)
+				}
+				return
+			}
+			var apierr *googleapi.Error
+			if !errors.As(err, &apierr) || apierr.Code != http.StatusUnauthorized {
+				t.Fatalf("Get() error = %v, want a 401 googleapi.Error", err)
+			}
+			if !strings.Contains(apierr.Message, tt.wantErr) {
+				t.Errorf("Get() error message = %q, want it to contain %q", apierr.Message, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCredentialsSourcePrecedence(t *testing.T) {
+	allEnv := map[string]string{
+		"GOOGLE_OAUTH_ACCESS_TOKEN":      "env-token",
+		"GOOGLE_CREDENTIALS":             "google-credentials",
+		"GOOGLE_CLOUD_KEYFILE_JSON":      "cloud-keyfile-json",
+		"GCLOUD_KEYFILE_JSON":            "gcloud-keyfile-json",
+		"GOOGLE_APPLICATION_CREDENTIALS": "application-credentials",
+	}
+	for _, tt := range []struct {
+		name    string
+		model   GoogleSiteVerificationProviderModel
+		env     []string
+		want    string
+		wantTok bool
+	}{
+		{
+			name:    "access_token attribute",
+			model:   GoogleSiteVerificationProviderModel{AccessToken: types.String{Value: "token"}},
+			env:     []string{"GOOGLE_OAUTH_ACCESS_TOKEN", "GOOGLE_CREDENTIALS"},
+			want:    "access_token",
+			wantTok: true,
+		},
+		{
+			name:  "credentials attribute",
+			model: GoogleSiteVerificationProviderModel{Credentials: types.String{Value: "{}"}},
+			env:   []string{"GOOGLE_OAUTH_ACCESS_TOKEN", "GOOGLE_CREDENTIALS"},
+			want:  "credentials",
+		},
+		{
+			name:    "access token env before credentials env",
+			env:     []string{"GOOGLE_OAUTH_ACCESS_TOKEN", "GOOGLE_CREDENTIALS", "GOOGLE_APPLICATION_CREDENTIALS"},
+			want:    "GOOGLE_OAUTH_ACCESS_TOKEN",
+			wantTok: true,
+		},
+		{
+			name: "GOOGLE_CREDENTIALS",
+			env:  []string{"GOOGLE_CREDENTIALS", "GOOGLE_CLOUD_KEYFILE_JSON", "GCLOUD_KEYFILE_JSON", "GOOGLE_APPLICATION_CREDENTIALS"},
+			want: "GOOGLE_CREDENTIALS",
+		},
+		{
+			name: "GOOGLE_CLOUD_KEYFILE_JSON",
+			env:  []string{"GOOGLE_CLOUD_KEYFILE_JSON", "GCLOUD_KEYFILE_JSON", "GOOGLE_APPLICATION_CREDENTIALS"},
+			want: "GOOGLE_CLOUD_KEYFILE_JSON",
+		},
+		{
+			name: "GCLOUD_KEYFILE_JSON",
+			env:  []string{"GCLOUD_KEYFILE_JSON", "GOOGLE_APPLICATION_CREDENTIALS"},
+			want: "GCLOUD_KEYFILE_JSON",
+		},
+		{
+			name: "GOOGLE_APPLICATION_CREDENTIALS",
+			env:  []string{"GOOGLE_APPLICATION_CREDENTIALS"},
+			want: "GOOGLE_APPLICATION_CREDENTIALS",
+		},
+		{
+			name: "application default credentials",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{}
+			for _, name := range tt.env {
+				env[name] = allEnv[name]
+			}
+			src, ok := tt.model.credentialsSource(func(name string) string { return env[name] })
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("credentialsSource() = %+v, want application default credentials", src)
+				}
+				return
+			}
+			if !ok || src.name != tt.want || src.accessToken != tt.wantTok {
+				t.Fatalf("credentialsSource() = %+v, %t, want %s (access token: %t)", src, ok, tt.want, tt.wantTok)
+			}
+			if want, ok := allEnv[tt.want]; ok && src.value != want {
+				t.Errorf("credentialsSource().value = %q, want %q", src.value, want)
+			}
+		})
+	}
+}
+
+func testServiceAccountKey(t *testing.T, tokenURI string) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "sa@example.iam.gserviceaccount.com",
+		"private_key_id": "key-id",
+		"private_key": string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})),
+		"token_uri": tokenURI,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestDelegatedCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if err := r.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			parts := strings.Split(r.Form.Get("assertion"), ".")
+			if len(parts) != 3 {
+				t.Fatalf("assertion has %d parts, want a JWT", len(parts))
+			}
+			payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var claims struct {
+				Iss   string `json:"iss"`
+				Sub   string `json:"sub"`
+				Scope string `json:"scope"`
+			}
+			if err := json.Unmarshal(payload, &claims); err != nil {
+				t.Fatal(err)
+			}
+			if claims.Iss != "sa@example.iam.gserviceaccount.com" || claims.Sub != "admin@example.com" || claims.Scope != siteverification.SiteverificationScope {
+				t.Errorf("claims = %+v", claims)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"delegated-token","token_type":"Bearer","expires_in":3600}`))
+		default:
+			if got := r.Header.Get("Authorization"); got != "Bearer delegated-token" {
+				t.Errorf("Authorization = %q", got)
+			}
+			_, _ = w.Write([]byte(`{"id":"dns%3A%2F%2Fexample.com"}`))
+		}
+	}))
+	defer srv.Close()
+
+	src := credentialsSource{name: "credentials", value: testServiceAccountKey(t, srv.URL+"/token"), attribute: true}
+	opt, diags := src.option(context.Background(), context.Background(), []string{siteverification.SiteverificationScope}, "admin@example.com")
+	if diags.HasError() {
+		t.Fatalf("option() returned errors: %v", diags)
+	}
+	svc, err := siteverification.NewService(context.Background(), opt, option.WithEndpoint(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.WebResource.Get("dns://example.com").Do(); err != nil {
+		t.Fatalf("Get() returned error: %s", err)
+	}
+}
+
+func TestDelegatedCredentialsUnsupported(t *testing.T) {
+	for _, name := range append([]string{accessTokenEnvVar}, credentialsEnvVars...) {
+		t.Setenv(name, "")
+	}
+	for _, tt := range []struct {
+		name  string
+		model GoogleSiteVerificationProviderModel
+		want  string
+	}{
+		{
+			name:  "application default credentials",
+			model: GoogleSiteVerificationProviderModel{},
+			want:  "the application default credentials",
+		},
+		{
+			name:  "access token",
+			model: GoogleSiteVerificationProviderModel{AccessToken: types.String{Value: "token"}},
+			want:  "the access token from access_token",
+		},
+		{
+			name:  "authorized user",
+			model: GoogleSiteVerificationProviderModel{Credentials: types.String{Value: `{"type":"authorized_user","client_id":"id","client_secret":"secret","refresh_token":"token"}`}},
+			want:  `are of type "authorized_user"`,
+		},
+		{
+			name:  "external account",
+			model: GoogleSiteVerificationProviderModel{Credentials: types.String{Value: testExternalAccount(map[string]interface{}{"file": "/dev/null"})}},
+			want:  `are of type "external_account"`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.model.Subject = types.String{Value: "admin@example.com"}
+			_, diags := tt.model.clientOptions(context.Background(), []string{siteverification.SiteverificationScope})
+			if !diags.HasError() {
+				t.Fatal("clientOptions() succeeded, want error")
+			}
+			if d := diags.Errors()[0]; !strings.Contains(d.Detail(), tt.want) {
+				t.Errorf("clientOptions() error = %q, want it to contain %q", d.Detail(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestOptions(t *testing.T) {
+	for _, name := range append([]string{accessTokenEnvVar}, credentialsEnvVars...) {
+		t.Setenv(name, "")
+	}
+	expiry := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"key-token","token_type":"Bearer","expires_in":3600}`))
+			return
+		}
+		if got := r.Header.Get("X-Goog-User-Project"); got != "billing-project" {
+			t.Errorf("%s X-Goog-User-Project = %q", r.URL.Path, got)
+		}
+		if got := r.Header.Get("X-Goog-Request-Reason"); got != "CHANGE-123" {
+			t.Errorf("%s X-Goog-Request-Reason = %q", r.URL.Path, got)
+		}
+		if strings.HasSuffix(r.URL.Path, ":generateAccessToken") {
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"accessToken": "impersonated-token",
+				"expireTime":  expiry,
+			})
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"dns%3A%2F%2Fexample.com"}`))
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		name  string
+		model GoogleSiteVerificationProviderModel
+	}{
+		{
+			name:  "access token",
+			model: GoogleSiteVerificationProviderModel{AccessToken: types.String{Value: "token"}},
+		},
+		{
+			name:  "credentials",
+			model: GoogleSiteVerificationProviderModel{Credentials: types.String{Value: testServiceAccountKey(t, srv.URL+"/token")}},
+		},
+		{
+			name: "impersonation",
+			model: GoogleSiteVerificationProviderModel{
+				AccessToken:                        types.String{Value: "token"},
+				ImpersonateServiceAccount:          types.String{Value: "target@example.iam.gserviceaccount.com"},
+				ImpersonateServiceAccountDelegates: types.List{Null: true, ElemType: types.StringType},
+				IAMCredentialsEndpoint:             types.String{Value: srv.URL},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.model.UserProjectOverride = types.Bool{Value: true}
+			tt.model.BillingProject = types.String{Value: "billing-project"}
+			tt.model.RequestReason = types.String{Value: "CHANGE-123"}
+			opts, diags := tt.model.clientOptions(context.Background(), []string{siteverification.SiteverificationScope})
+			if diags.HasError() {
+				t.Fatalf("clientOptions() returned errors: %v", diags)
+			}
+			svc, err := siteverification.NewService(context.Background(), append(opts, option.WithEndpoint(srv.URL))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := svc.WebResource.Get("dns://example.com").Do(); err != nil {
+				t.Fatalf("Get() returned error: %s", err)
+			}
+		})
+	}
+}
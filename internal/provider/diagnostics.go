@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+
+	"giautm.dev/googlesiteverification/internal/apierror"
+	"giautm.dev/googlesiteverification/internal/siteid"
+)
+
+// errorPaths are the attributes that diagnostics of API errors are attached
+// to. An empty path leaves the diagnostic unattached, as in ImportState.
+//
+// A resource cannot point at the provider block, so errors caused by the
+// credentials are attached to the domain and name the credentials instead.
+type errorPaths struct {
+	domain path.Path
+	token  path.Path
+}
+
+// apiErrorHelp describes a class of API errors to the user.
+type apiErrorHelp struct {
+	// summary is formatted with the site id, such as dns://example.com.
+	summary string
+	hint    string
+	// token reports whether the token attribute is at fault.
+	token bool
+}
+
+var apiErrorHelps = map[apierror.Kind]apiErrorHelp{
+	apierror.TokenNotFound: {
+		summary: "Domain Not Verified: Token Not Found in DNS",
+		hint: "Publish the token as a TXT record of the domain, for example from the record_* attributes of the googlesiteverification_domain data source, " +
+			"and make the verification depend on that record. If the record exists, DNS may not have propagated yet: raise the create timeout.",
+		token: true,
+	},
+	apierror.TokenStillPresent: {
+		summary: "Domain Not Unverified: Token Still in DNS",
+		hint: "Remove the TXT record holding the token before unverifying, for example by making the verification depend on the record so that it is destroyed first. " +
+			"If the record is gone, DNS may not have propagated yet: raise the delete timeout.",
+		token: true,
+	},
+	apierror.NotOwner: {
+		summary: "Credentials Lack Ownership of %s",
+		hint: "Configure the provider credentials with an identity that owns the site, " +
+			"or add the identity of the credentials as an owner in Search Console.",
+	},
+	apierror.NotFound: {
+		summary: "Verification of %s Not Found",
+		hint: "The verification does not exist for the identity of the provider credentials. " +
+			"It may have been removed outside of Terraform, or belong to other credentials.",
+	},
+	apierror.Quota: {
+		summary: "Site Verification API Quota Exceeded",
+		hint: "Wait for the quota to refill and apply again. Lower the concurrency and requests_per_second of domain sets, " +
+			"or request a higher quota for the project of the credentials or billing_project.",
+	},
+	apierror.Transient: {
+		summary: "Site Verification API Unavailable",
+		hint:    "The API failed temporarily. Apply again later.",
+	},
+}
+
+// addClientError adds a diagnostic for err, which failed action (such as
+// "create verification") on domain. Errors classified by the apierror
+// package get a specific summary and a remediation hint, and are attached to
+// the attribute at fault.
+func addClientError(diags *diag.Diagnostics, paths errorPaths, action, domain string, err error) {
+	detail := fmt.Sprintf("Unable to %s, got error: %s", action, err)
+	help, ok := apiErrorHelps[apierror.Classify(err)]
+	if !ok {
+		diags.AddError("Client Error", detail)
+		return
+	}
+
+	summary := help.summary
+	if strings.Contains(summary, "%s") {
+		summary = fmt.Sprintf(summary, siteid.Site{Type: siteid.TypeDomain, Identifier: domain}.ID())
+	}
+	detail += "\n\n" + help.hint
+	attr := paths.domain
+	if help.token && len(paths.token.Steps()) > 0 {
+		attr = paths.token
+	}
+	if len(attr.Steps()) == 0 {
+		diags.AddError(summary, detail)
+		return
+	}
+	diags.AddAttributeError(attr, summary, detail)
+}
+
+// domainErrorsSummary returns the summary of the errors of several domains,
+// which is specific when they all share a class and fallback otherwise.
+func domainErrorsSummary(errs map[string]error, fallback string) string {
+	kind := apierror.Unknown
+	for _, err := range errs {
+		k := apierror.Classify(err)
+		if k == apierror.Unknown || (kind != apierror.Unknown && k != kind) {
+			return fallback
+		}
+		kind = k
+	}
+	help, ok := apiErrorHelps[kind]
+	if !ok || strings.Contains(help.summary, "%s") {
+		return fallback
+	}
+	return help.summary
+}
+
+// domainErrors returns the detail of the errors of several domains, one line
+// each, followed by the hints of domainErrorsHints.
+func domainErrors(action string, errs map[string]error, total int) string {
+	failed := make([]string, 0, len(errs))
+	for domain, err := range errs {
+		failed = append(failed, fmt.Sprintf("- %s: %s", domain, err))
+	}
+	sort.Strings(failed)
+	return fmt.Sprintf("Unable to %s for %d of %d domains, got errors:\n%s",
+		action, len(errs), total, strings.Join(failed, "\n")) + domainErrorsHints(errs)
+}
+
+// domainErrorsHints returns the remediation hints of the classes of errs, one
+// paragraph each, or an empty string.
+func domainErrorsHints(errs map[string]error) string {
+	seen := map[apierror.Kind]bool{}
+	var hints []string
+	for _, domain := range sortedKeys(errs) {
+		kind := apierror.Classify(errs[domain])
+		if help, ok := apiErrorHelps[kind]; ok && !seen[kind] {
+			seen[kind] = true
+			hints = append(hints, help.hint)
+		}
+	}
+	if len(hints) == 0 {
+		return ""
+	}
+	return "\n\n" + strings.Join(hints, "\n\n")
+}
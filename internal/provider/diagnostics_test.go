@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"google.golang.org/api/googleapi"
+)
+
+func TestAddClientError(t *testing.T) {
+	paths := errorPaths{domain: path.Root("domain"), token: path.Root("token")}
+	for name, tt := range map[string]struct {
+		err         error
+		paths       errorPaths
+		wantSummary string
+		wantPath    path.Path
+		wantHint    string
+	}{
+		"token not found": {
+			err:         &googleapi.Error{Code: http.StatusBadRequest, Message: "The necessary verification token could not be found on your site."},
+			paths:       paths,
+			wantSummary: "Domain Not Verified: Token Not Found in DNS",
+			wantPath:    path.Root("token"),
+			wantHint:    "Publish the token as a TXT record",
+		},
+		"token still present": {
+			err:         fmt.Errorf("retrying: %w", &googleapi.Error{Code: http.StatusBadRequest, Message: "You cannot unverify your ownership of this site until your verification token (DNS record) has been removed."}),
+			paths:       paths,
+			wantSummary: "Domain Not Unverified: Token Still in DNS",
+			wantPath:    path.Root("token"),
+			wantHint:    "Remove the TXT record",
+		},
+		"not owner": {
+			err:         &googleapi.Error{Code: http.StatusForbidden, Message: "You are not an owner of this site."},
+			paths:       paths,
+			wantSummary: "Credentials Lack Ownership of dns://example.com",
+			wantPath:    path.Root("domain"),
+			wantHint:    "Configure the provider credentials",
+		},
+		"token without a token attribute": {
+			err:         &googleapi.Error{Code: http.StatusBadRequest, Message: "The necessary verification token could not be found on your site."},
+			paths:       errorPaths{domain: path.Root("id")},
+			wantSummary: "Domain Not Verified: Token Not Found in DNS",
+			wantPath:    path.Root("id"),
+		},
+		"unattached": {
+			err:         &googleapi.Error{Code: http.StatusNotFound, Message: "Not Found"},
+			wantSummary: "Verification of dns://example.com Not Found",
+			wantPath:    path.Empty(),
+		},
+		"unknown": {
+			err:         errors.New("boom"),
+			paths:       paths,
+			wantSummary: "Client Error",
+			wantPath:    path.Empty(),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var diags diag.Diagnostics
+			addClientError(&diags, tt.paths, "create verification", "example.com", tt.err)
+			if len(diags) != 1 || !diags.HasError() {
+				t.Fatalf("diagnostics = %v, want one error", diags)
+			}
+			d := diags[0]
+			if d.Summary() != tt.wantSummary {
+				t.Errorf("summary = %q, want %q", d.Summary(), tt.wantSummary)
+			}
+			var got path.Path
+			if withPath, ok := d.(diag.DiagnosticWithPath); ok {
+				got = withPath.Path()
+			}
+			if got.String() != tt.wantPath.String() {
+				t.Errorf("path = %s, want %s", got, tt.wantPath)
+			}
+			if !strings.HasPrefix(d.Detail(), "Unable to create verification, got error: "+tt.err.Error()) {
+				t.Errorf("detail = %q, want the error", d.Detail())
+			}
+			if !strings.Contains(d.Detail(), tt.wantHint) {
+				t.Errorf("detail = %q, want the hint %q", d.Detail(), tt.wantHint)
+			}
+		})
+	}
+}
+
+func TestDomainErrorsSummary(t *testing.T) {
+	quota := &googleapi.Error{Code: http.StatusTooManyRequests, Message: "Quota exceeded."}
+	notOwner := &googleapi.Error{Code: http.StatusForbidden, Message: "You are not an owner of this site."}
+	for name, tt := range map[string]struct {
+		errs map[string]error
+		want string
+	}{
+		"same class":      {errs: map[string]error{"a.com": quota, "b.com": quota}, want: "Site Verification API Quota Exceeded"},
+		"mixed classes":   {errs: map[string]error{"a.com": quota, "b.com": notOwner}, want: "Client Error"},
+		"per site class":  {errs: map[string]error{"a.com": notOwner}, want: "Client Error"},
+		"unknown classes": {errs: map[string]error{"a.com": errors.New("boom")}, want: "Client Error"},
+	} {
+		if got := domainErrorsSummary(tt.errs, "Client Error"); got != tt.want {
+			t.Errorf("%s: domainErrorsSummary() = %q, want %q", name, got, tt.want)
+		}
+	}
+
+	hints := domainErrorsHints(map[string]error{"a.com": quota, "b.com": quota, "c.com": notOwner})
+	if strings.Count(hints, "Wait for the quota") != 1 || !strings.Contains(hints, "Configure the provider credentials") {
+		t.Errorf("domainErrorsHints() = %q, want each hint once", hints)
+	}
+}
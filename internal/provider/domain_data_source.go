@@ -8,36 +8,46 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework-timeouts/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-	"google.golang.org/api/siteverification/v1"
+
+	"giautm.dev/googlesiteverification/internal/domainname"
+	"giautm.dev/googlesiteverification/internal/records"
 )
 
 type (
 	// DomainDataSource defines the data source implementation.
 	DomainDataSource struct {
-		srv *siteverification.Service
+		client apiClient
 	}
 	// DomainDataSourceModel describes the data source data model.
 	DomainDataSourceModel struct {
-		ID          types.String `tfsdk:"id"`
-		RecordType  types.String `tfsdk:"record_type"`
-		RecordName  types.String `tfsdk:"record_name"`
-		RecordValue types.String `tfsdk:"record_value"`
-		Timeouts    types.Object `tfsdk:"timeouts"`
+		ID                 types.String `tfsdk:"id"`
+		Zone               types.String `tfsdk:"zone"`
+		RecordType         types.String `tfsdk:"record_type"`
+		RecordName         types.String `tfsdk:"record_name"`
+		RecordNameRelative types.String `tfsdk:"record_name_relative"`
+		RecordFQDN         types.String `tfsdk:"record_fqdn"`
+		RecordValue        types.String `tfsdk:"record_value"`
+		TTL                types.Int64  `tfsdk:"ttl"`
+		BIND               types.String `tfsdk:"bind"`
+		Route53ChangeBatch types.String `tfsdk:"route53_change_batch"`
+		OctoDNS            types.String `tfsdk:"octodns"`
+		DNSControl         types.String `tfsdk:"dnscontrol"`
+		JSON               types.String `tfsdk:"json"`
+		Timeouts           types.Object `tfsdk:"timeouts"`
 	}
 )
 
 // Ensure provider defined types fully satisfy framework interfaces
 var (
-	_ datasource.DataSource = &DomainDataSource{}
+	_ datasource.DataSource                   = &DomainDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &DomainDataSource{}
 )
 
-const (
-	resourceType       = "INET_DOMAIN"
-	verificationMethod = "DNS_TXT"
-)
+const defaultRecordTTL = 300
 
 func NewDomainDataSource() datasource.DataSource {
 	return &DomainDataSource{}
@@ -52,9 +62,20 @@ func (d *DomainDataSource) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Di
 		MarkdownDescription: "The Domain data source provides a token for verifying domain ownership.",
 		Attributes: map[string]tfsdk.Attribute{
 			"id": {
-				MarkdownDescription: "The domain you want to verify.",
+				MarkdownDescription: "The domain you want to verify. It is lowercased, stripped of a trailing dot and converted to punycode before use.",
 				Type:                types.StringType,
 				Required:            true,
+				Validators: []tfsdk.AttributeValidator{
+					domainValidator{},
+				},
+			},
+			"zone": {
+				MarkdownDescription: "The DNS zone the record will be created in, for example `example.com`. When set, `record_name_relative` is relative to this zone. The domain must be inside the zone.",
+				Type:                types.StringType,
+				Optional:            true,
+				Validators: []tfsdk.AttributeValidator{
+					domainValidator{},
+				},
 			},
 			"record_type": {
 				MarkdownDescription: "The type of DNS record you should create.",
@@ -62,7 +83,17 @@ func (d *DomainDataSource) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Di
 				Computed:            true,
 			},
 			"record_name": {
-				MarkdownDescription: "The name of the record you should create.",
+				MarkdownDescription: "The name of the record you should create, in normalized ASCII form.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"record_name_relative": {
+				MarkdownDescription: "The name of the record relative to `zone`, or `@` for the zone apex. Equal to `record_name` when `zone` is not set.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"record_fqdn": {
+				MarkdownDescription: "The fully qualified name of the record, with a trailing dot.",
 				Type:                types.StringType,
 				Computed:            true,
 			},
@@ -71,6 +102,37 @@ func (d *DomainDataSource) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Di
 				Type:                types.StringType,
 				Computed:            true,
 			},
+			"ttl": {
+				MarkdownDescription: fmt.Sprintf("The TTL used by the rendered records. Defaults to `%d`.", defaultRecordTTL),
+				Type:                types.Int64Type,
+				Optional:            true,
+				Computed:            true,
+			},
+			"bind": {
+				MarkdownDescription: "The record as a BIND zone file line.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"route53_change_batch": {
+				MarkdownDescription: "The record as an AWS Route53 `ChangeBatch` JSON document that upserts it.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"octodns": {
+				MarkdownDescription: "The record as an octoDNS YAML entry, keyed by `record_name_relative`.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"dnscontrol": {
+				MarkdownDescription: "The record as a dnscontrol record modifier, named by `record_name_relative`.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"json": {
+				MarkdownDescription: "The record as a JSON object with `name`, `fqdn`, `type`, `ttl` and `value` keys.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
 		},
 		Blocks: map[string]tfsdk.Block{
 			"timeouts": timeouts.Block(ctx, timeouts.Opts{
@@ -86,15 +148,49 @@ func (d *DomainDataSource) Configure(ctx context.Context, req datasource.Configu
 		return
 	}
 
-	srv, ok := req.ProviderData.(*siteverification.Service)
+	data, ok := req.ProviderData.(apiClient)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *siteverification.Service, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected apiClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 		return
 	}
-	d.srv = srv
+	d.client = data
+}
+
+func (d *DomainDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var data DomainDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if !data.TTL.IsNull() && !data.TTL.IsUnknown() && data.TTL.Value < 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("ttl"),
+			"Invalid TTL",
+			fmt.Sprintf("The TTL must not be negative, got: %d.", data.TTL.Value),
+		)
+	}
+	if data.ID.IsNull() || data.ID.IsUnknown() || data.Zone.IsNull() || data.Zone.IsUnknown() {
+		return
+	}
+
+	domain, err := domainname.Normalize(data.ID.Value)
+	if err != nil {
+		// Reported by the attribute validator.
+		return
+	}
+	zone, err := domainname.Normalize(data.Zone.Value)
+	if err != nil {
+		return
+	}
+	if _, err := domainname.Relative(domain, zone); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("zone"),
+			"Domain Outside Zone",
+			fmt.Sprintf("The domain %q must be the zone %q itself or a subdomain of it.", data.ID.Value, data.Zone.Value),
+		)
+	}
 }
 
 func (d *DomainDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -106,23 +202,36 @@ func (d *DomainDataSource) Read(ctx context.Context, req datasource.ReadRequest,
 		return
 	}
 
+	domain, err := domainname.Normalize(data.ID.Value)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("id"),
+			"Invalid Domain",
+			fmt.Sprintf("Unable to use %q as a domain name: %s", data.ID.Value, err),
+		)
+		return
+	}
+
+	relative := domain
+	if !data.Zone.IsNull() {
+		zone, err := domainname.Normalize(data.Zone.Value)
+		if err == nil {
+			relative, err = domainname.Relative(domain, zone)
+		}
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("zone"),
+				"Invalid Zone",
+				fmt.Sprintf("Unable to use %q as the zone of %q: %s", data.Zone.Value, data.ID.Value, err),
+			)
+			return
+		}
+	}
+
 	readTimeout := timeouts.Read(ctx, data.Timeouts, 60*time.Second)
 	ctx, cancel := context.WithTimeout(ctx, readTimeout)
 	defer cancel()
-	result, err := d.srv.WebResource.
-		GetToken(&siteverification.SiteVerificationWebResourceGettokenRequest{
-			Site: &siteverification.SiteVerificationWebResourceGettokenRequestSite{
-				Identifier: data.ID.Value,
-				Type:       resourceType,
-			},
-			VerificationMethod: verificationMethod,
-		}).
-		Context(ctx).Do()
+	token, err := d.client.GetToken(ctx, domain)
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"Client Error",
-			fmt.Sprintf("Unable to read DNS Token, got error: %s", err),
-		)
+		addClientError(&resp.Diagnostics, errorPaths{domain: path.Root("id")}, "read DNS Token", domain, err)
 		return
 	}
 
@@ -131,9 +240,46 @@ func (d *DomainDataSource) Read(ctx context.Context, req datasource.ReadRequest,
 	tflog.Trace(ctx, "read a data source")
 
 	data.RecordType = types.String{Value: "TXT"}
-	data.RecordName = data.ID
-	data.RecordValue = types.String{Value: result.Token}
+	data.RecordName = types.String{Value: domain}
+	data.RecordNameRelative = types.String{Value: relative}
+	data.RecordFQDN = types.String{Value: domainname.FQDN(domain)}
+	if data.TTL.IsNull() || data.TTL.IsUnknown() {
+		data.TTL = types.Int64{Value: defaultRecordTTL}
+	}
+	data.RecordValue = types.String{Value: token}
+	resp.Diagnostics.Append(data.render()...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
+
+// render fills the rendered record attributes from the record attributes.
+func (m *DomainDataSourceModel) render() diag.Diagnostics {
+	var diags diag.Diagnostics
+	record := records.Record{
+		Name:  m.RecordNameRelative.Value,
+		FQDN:  m.RecordFQDN.Value,
+		Type:  m.RecordType.Value,
+		TTL:   m.TTL.Value,
+		Value: m.RecordValue.Value,
+	}
+	route53, err := records.Route53ChangeBatch(record)
+	if err != nil {
+		diags.AddError("Render Error", fmt.Sprintf("Unable to render Route53 change batch, got error: %s", err))
+		return diags
+	}
+	generic, err := records.JSON(record)
+	if err != nil {
+		diags.AddError("Render Error", fmt.Sprintf("Unable to render JSON record, got error: %s", err))
+		return diags
+	}
+	m.BIND = types.String{Value: records.BIND(record)}
+	m.Route53ChangeBatch = types.String{Value: route53}
+	m.OctoDNS = types.String{Value: records.OctoDNS(record)}
+	m.DNSControl = types.String{Value: records.DNSControl(record)}
+	m.JSON = types.String{Value: generic}
+	return diags
+}
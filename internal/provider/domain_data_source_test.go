@@ -1,15 +1,18 @@
 package provider_test
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 )
 
 func TestAccDomainDataSource(t *testing.T) {
+	factories := testAccCassetteProviderFactories(t, "")
+
 	resource.Test(t, resource.TestCase{
 		PreCheck:                 func() { testAccPreCheck(t) },
-		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		ProtoV6ProviderFactories: factories,
 		Steps: []resource.TestStep{
 			// Read testing
 			{
@@ -18,6 +21,17 @@ func TestAccDomainDataSource(t *testing.T) {
 					resource.TestCheckResourceAttr("data.googlesiteverification_domain.test", "id", "example.com"),
 				),
 			},
+			{
+				Config: testAccDomainDataSourceZoneConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.googlesiteverification_domain.test", "record_name", "app.eu.example.com"),
+					resource.TestCheckResourceAttr("data.googlesiteverification_domain.test", "record_name_relative", "app.eu"),
+					resource.TestCheckResourceAttr("data.googlesiteverification_domain.test", "record_fqdn", "app.eu.example.com."),
+					resource.TestCheckResourceAttr("data.googlesiteverification_domain.test", "ttl", "300"),
+					resource.TestMatchResourceAttr("data.googlesiteverification_domain.test", "bind", regexp.MustCompile(`^app\.eu\.example\.com\.\t300\tIN\tTXT\t"google-site-verification=[A-Za-z0-9_-]+"$`)),
+					resource.TestMatchResourceAttr("data.googlesiteverification_domain.test", "dnscontrol", regexp.MustCompile(`^TXT\("app\.eu", `)),
+				),
+			},
 		},
 	})
 }
@@ -27,3 +41,10 @@ data "googlesiteverification_domain" "test" {
   id = "example.com"
 }
 `
+
+const testAccDomainDataSourceZoneConfig = `
+data "googlesiteverification_domain" "test" {
+  id   = "App.EU.example.com."
+  zone = "example.com"
+}
+`
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"giautm.dev/googlesiteverification/internal/fake"
+)
+
+func TestDomainDataSourceRead(t *testing.T) {
+	for name, tt := range map[string]struct {
+		values       map[string]tftypes.Value
+		inject       func(*fake.Server)
+		wantErr      string
+		wantRelative string
+	}{
+		"domain": {
+			values: map[string]tftypes.Value{
+				"id": tftypes.NewValue(tftypes.String, "WWW.Example.com."),
+			},
+			wantRelative: "www.example.com",
+		},
+		"zone": {
+			values: map[string]tftypes.Value{
+				"id":   tftypes.NewValue(tftypes.String, "www.example.com"),
+				"zone": tftypes.NewValue(tftypes.String, "example.com"),
+			},
+			wantRelative: "www",
+		},
+		"api error": {
+			values: map[string]tftypes.Value{
+				"id": tftypes.NewValue(tftypes.String, "www.example.com"),
+			},
+			inject: func(s *fake.Server) {
+				s.Inject(fake.MethodGetToken, http.StatusTooManyRequests, "Quota exceeded.", 1)
+			},
+			wantErr: "Quota exceeded.",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, pd := testFake(t)
+			if tt.inject != nil {
+				tt.inject(s)
+			}
+
+			d := &DomainDataSource{}
+			configureResp := &datasource.ConfigureResponse{}
+			d.Configure(ctx, datasource.ConfigureRequest{ProviderData: pd}, configureResp)
+			wantDiagnostic(t, configureResp.Diagnostics, "")
+			schema, diags := d.GetSchema(ctx)
+			wantDiagnostic(t, diags, "")
+
+			config := tfsdk.Config{Schema: schema, Raw: testObject(t, schema, tt.values)}
+			resp := &datasource.ReadResponse{State: tfsdk.State{Schema: schema, Raw: testObject(t, schema, nil)}}
+			d.Read(ctx, datasource.ReadRequest{Config: config}, resp)
+
+			wantDiagnostic(t, resp.Diagnostics, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+			var data DomainDataSourceModel
+			resp.Diagnostics.Append(resp.State.Get(ctx, &data)...)
+			wantDiagnostic(t, resp.Diagnostics, "")
+			token := s.Token("www.example.com")
+			if data.RecordValue.Value != token || data.RecordName.Value != "www.example.com" ||
+				data.RecordNameRelative.Value != tt.wantRelative || data.TTL.Value != defaultRecordTTL {
+				t.Errorf("Read() state = %+v", data)
+			}
+			if want := "www.example.com.\t300\tIN\tTXT\t\"" + token + "\""; data.BIND.Value != want {
+				t.Errorf("bind = %q, want %q", data.BIND.Value, want)
+			}
+			for name, got := range map[string]types.String{"route53_change_batch": data.Route53ChangeBatch, "octodns": data.OctoDNS, "dnscontrol": data.DNSControl, "json": data.JSON} {
+				if !strings.Contains(got.Value, token) {
+					t.Errorf("%s = %q, want the token", name, got.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestDomainDataSourceRender(t *testing.T) {
+	const token = "google-site-verification=abc123"
+	data := DomainDataSourceModel{
+		RecordType:         types.String{Value: "TXT"},
+		RecordName:         types.String{Value: "www.example.com"},
+		RecordNameRelative: types.String{Value: "www"},
+		RecordFQDN:         types.String{Value: "www.example.com."},
+		RecordValue:        types.String{Value: token},
+		TTL:                types.Int64{Value: defaultRecordTTL},
+	}
+	wantDiagnostic(t, data.render(), "")
+	for name, got := range map[string]types.String{
+		"bind":                 data.BIND,
+		"route53_change_batch": data.Route53ChangeBatch,
+		"octodns":              data.OctoDNS,
+		"dnscontrol":           data.DNSControl,
+		"json":                 data.JSON,
+	} {
+		if !strings.Contains(got.Value, token) {
+			t.Errorf("%s = %q, want the token %q", name, got.Value, token)
+		}
+	}
+}
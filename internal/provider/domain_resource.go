@@ -2,33 +2,32 @@ package provider
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"net/url"
-	"strings"
-	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework-timeouts/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-	"google.golang.org/api/googleapi"
-	"google.golang.org/api/siteverification/v1"
+
+	"giautm.dev/googlesiteverification/internal/domainname"
+	"giautm.dev/googlesiteverification/internal/siteid"
 )
 
 type (
 	// DomainResource defines the resource implementation.
 	DomainResource struct {
-		srv *siteverification.Service
+		client apiClient
 	}
 	// DomainResourceModel describes the resource data model.
 	DomainResourceModel struct {
-		Domain   types.String `tfsdk:"domain"`
-		Token    types.String `tfsdk:"token"`
-		Id       types.String `tfsdk:"id"`
-		Timeouts types.Object `tfsdk:"timeouts"`
+		Domain           types.String `tfsdk:"domain"`
+		NormalizedDomain types.String `tfsdk:"normalized_domain"`
+		Token            types.String `tfsdk:"token"`
+		Id               types.String `tfsdk:"id"`
+		Timeouts         types.Object `tfsdk:"timeouts"`
 	}
 )
 
@@ -37,9 +36,9 @@ var (
 	_ resource.Resource                = &DomainResource{}
 	_ resource.ResourceWithImportState = &DomainResource{}
 
-	errTokenNotFound = "The necessary verification token could not be found on your site."
-	errTokenExists   = "You cannot unverify your ownership of this site until your verification token (meta tag, HTML file, Google Analytics tracking code, Google Tag Manager container code, or DNS record) has been removed."
-	sleepSeconds     = 60 * time.Second
+	// domainResourceErrorPaths attach API errors to the configuration of a
+	// domain resource.
+	domainResourceErrorPaths = errorPaths{domain: path.Root("domain"), token: path.Root("token")}
 )
 
 func NewDomainResource() resource.Resource {
@@ -52,14 +51,25 @@ func (r *DomainResource) Metadata(ctx context.Context, req resource.MetadataRequ
 
 func (r *DomainResource) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
-		MarkdownDescription: "Manages a DNS verification for a domain.",
+		MarkdownDescription: "Manages a DNS verification for a domain. With only the `siteverification.verify_only` provider scope, refresh and import trust the state instead of reading the verification, and destroying it leaves the verification in place.",
 		Attributes: map[string]tfsdk.Attribute{
 			"domain": {
-				MarkdownDescription: "The domain you want to verify.",
+				MarkdownDescription: "The domain you want to verify. Internationalized names, mixed case and a trailing dot are accepted; changes that normalize to the same domain do not force a new verification.",
 				Required:            true,
 				Type:                types.StringType,
+				Validators: []tfsdk.AttributeValidator{
+					domainValidator{},
+				},
 				PlanModifiers: tfsdk.AttributePlanModifiers{
-					resource.RequiresReplace(),
+					requiresReplaceIfDomainChanged(),
+				},
+			},
+			"normalized_domain": {
+				MarkdownDescription: "The lowercased, punycode form of `domain` without a trailing dot, as sent to the API.",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					normalizeDomain(path.Root("domain")),
 				},
 			},
 			"token": {
@@ -95,15 +105,15 @@ func (r *DomainResource) Configure(ctx context.Context, req resource.ConfigureRe
 		return
 	}
 
-	srv, ok := req.ProviderData.(*siteverification.Service)
+	data, ok := req.ProviderData.(apiClient)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *siteverification.Service, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected apiClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 		return
 	}
-	r.srv = srv
+	r.client = data
 }
 
 func (r *DomainResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
@@ -115,42 +125,15 @@ func (r *DomainResource) Create(ctx context.Context, req resource.CreateRequest,
 		return
 	}
 
-	createTimeout := timeouts.Create(ctx, data.Timeouts, sleepSeconds*5)
+	createTimeout := timeouts.Create(ctx, data.Timeouts, defaultTimeout)
 	ctx, cancel := context.WithTimeout(ctx, createTimeout)
 	defer cancel()
-	for {
-		result, err := r.srv.WebResource.
-			Insert(verificationMethod, &siteverification.SiteVerificationWebResourceResource{
-				Site: &siteverification.SiteVerificationWebResourceResourceSite{
-					Identifier: data.Domain.Value,
-					Type:       resourceType,
-				},
-			}).
-			Context(ctx).Do()
-		if err != nil {
-			if checkErr(err, errTokenNotFound) {
-				tflog.Warn(ctx, "Trying to create verification again")
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(sleepSeconds):
-				}
-				continue
-			}
-			resp.Diagnostics.AddError("Client Error",
-				fmt.Sprintf("Unable to create verification, got error: %s", err))
-			return
-		}
-
-		id, err := url.QueryUnescape(result.Id)
-		if err != nil {
-			resp.Diagnostics.AddError("Client Error",
-				fmt.Sprintf("failed to urldecode id %s, %s", result.Id, err))
-			return
-		}
-		data.Id = types.String{Value: id}
-		break
+	id, err := newVerifier(r.client, nil, r.client.RetryInterval()).Verify(ctx, data.NormalizedDomain.Value)
+	if err != nil {
+		addClientError(&resp.Diagnostics, domainResourceErrorPaths, "create verification", data.NormalizedDomain.Value, err)
+		return
 	}
+	data.Id = types.String{Value: id}
 
 	// Write logs using the tflog package
 	// Documentation: https://terraform.io/plugin/log
@@ -169,13 +152,37 @@ func (r *DomainResource) Read(ctx context.Context, req resource.ReadRequest, res
 		return
 	}
 
-	readTimeout := timeouts.Read(ctx, data.Timeouts, sleepSeconds*5)
+	site, err := siteid.Parse(data.Id.Value)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid ID",
+			fmt.Sprintf("Unable to parse verification id, got error: %s", err))
+		return
+	}
+	// State written before normalized_domain was added has it null, fill it
+	// so that the upgrade does not plan an update.
+	if data.NormalizedDomain.IsNull() {
+		normalized, err := domainname.Normalize(data.Domain.Value)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("domain"), "Invalid Domain",
+				fmt.Sprintf("Unable to normalize domain, got error: %s", err))
+			return
+		}
+		data.NormalizedDomain = types.String{Value: normalized}
+	}
+
+	if !r.client.Capabilities().read {
+		resp.Diagnostics.AddWarning("Verification Not Refreshed",
+			fmt.Sprintf("The provider scopes do not permit reading verifications, so %s is assumed to be still verified.", site.Identifier))
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	readTimeout := timeouts.Read(ctx, data.Timeouts, defaultTimeout)
 	ctx, cancel := context.WithTimeout(ctx, readTimeout)
 	defer cancel()
-	_, err := r.srv.WebResource.Get(data.Id.Value).Context(ctx).Do()
+	_, err = r.client.Get(ctx, site.ID())
 	if err != nil {
-		resp.Diagnostics.AddError("Client Error",
-			fmt.Sprintf("Unable to read verification, got error: %s", err))
+		addClientError(&resp.Diagnostics, domainResourceErrorPaths, "read verification", site.Identifier, err)
 		return
 	}
 
@@ -184,7 +191,17 @@ func (r *DomainResource) Read(ctx context.Context, req resource.ReadRequest, res
 }
 
 func (r *DomainResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	// Do nothing because we have RequiresReplace on domain and token
+	var data *DomainResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Only the spelling of the domain can change in place, the verification
+	// itself is replaced when the normalized domain or token changes.
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *DomainResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -196,60 +213,67 @@ func (r *DomainResource) Delete(ctx context.Context, req resource.DeleteRequest,
 		return
 	}
 
-	deleteTimeout := timeouts.Delete(ctx, data.Timeouts, sleepSeconds*5)
+	site, err := siteid.Parse(data.Id.Value)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid ID",
+			fmt.Sprintf("Unable to parse verification id, got error: %s", err))
+		return
+	}
+
+	if !r.client.Capabilities().unverify {
+		resp.Diagnostics.AddWarning("Verification Not Deleted",
+			fmt.Sprintf("The provider scopes do not permit unverifying domains, so %s is removed from state but stays verified. Remove it in Search Console or with the https://www.googleapis.com/auth/siteverification scope.", site.Identifier))
+		return
+	}
+
+	deleteTimeout := timeouts.Delete(ctx, data.Timeouts, defaultTimeout)
 	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
 	defer cancel()
-	for {
-		err := r.srv.WebResource.Delete(data.Id.Value).Context(ctx).Do()
-		if err != nil {
-			if checkErr(err, errTokenExists) {
-				tflog.Warn(ctx, "Trying to delete verification again")
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(sleepSeconds):
-				}
-				continue
-			}
-			resp.Diagnostics.AddError("Client Error",
-				fmt.Sprintf("Unable to delete verification, got error: %s", err))
-		}
-		return
+	if err := newVerifier(r.client, nil, r.client.RetryInterval()).Unverify(ctx, site.ID()); err != nil {
+		addClientError(&resp.Diagnostics, domainResourceErrorPaths, "delete verification", site.Identifier, err)
 	}
 }
 
 func (r *DomainResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	_, err := r.srv.WebResource.Get(req.ID).Context(ctx).Do()
+	site, err := siteid.Parse(req.ID)
 	if err != nil {
-		resp.Diagnostics.AddError("Client Error",
-			fmt.Sprintf("Unable to import verification, got error: %s", err))
+		resp.Diagnostics.AddError("Invalid Import ID",
+			fmt.Sprintf("Expected a domain (example.com or dns://example.com), got error: %s", err))
 		return
 	}
-	domain := strings.TrimPrefix(req.ID, "dns://")
-
-	result, err := r.srv.WebResource.
-		GetToken(&siteverification.SiteVerificationWebResourceGettokenRequest{
-			Site: &siteverification.SiteVerificationWebResourceGettokenRequestSite{
-				Identifier: domain,
-				Type:       resourceType,
-			},
-			VerificationMethod: verificationMethod,
-		}).
-		Context(ctx).Do()
+	if !site.IsDomain() {
+		resp.Diagnostics.AddError("Invalid Import ID",
+			fmt.Sprintf("Only domain verifications can be imported, got URL-prefix site %q.", site.Identifier))
+		return
+	}
+	domain, err := domainname.Normalize(site.Identifier)
 	if err != nil {
-		resp.Diagnostics.AddError("Client Error",
+		resp.Diagnostics.AddError("Invalid Import ID",
 			fmt.Sprintf("Unable to import verification, got error: %s", err))
 		return
 	}
+	id := siteid.Site{Type: siteid.TypeDomain, Identifier: domain}.ID()
 
-	resp.Diagnostics.Append(resp.State.Set(ctx, &DomainResourceModel{
-		Id:     types.String{Value: req.ID},
-		Domain: types.String{Value: domain},
-		Token:  types.String{Value: result.Token},
-	})...)
-}
+	if r.client.Capabilities().read {
+		_, err = r.client.Get(ctx, id)
+		if err != nil {
+			addClientError(&resp.Diagnostics, errorPaths{}, "import verification", domain, err)
+			return
+		}
+	} else {
+		resp.Diagnostics.AddWarning("Verification Not Checked",
+			fmt.Sprintf("The provider scopes do not permit reading verifications, so %s is imported without checking that it is verified.", domain))
+	}
+
+	token, err := r.client.GetToken(ctx, domain)
+	if err != nil {
+		addClientError(&resp.Diagnostics, errorPaths{}, "import verification", domain, err)
+		return
+	}
 
-func checkErr(err error, msg string) bool {
-	var apierr *googleapi.Error
-	return errors.As(err, &apierr) && apierr.Code == 400 && strings.Contains(apierr.Message, msg)
+	// Set the attributes one by one so that the timeouts block stays null.
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), id)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("domain"), domain)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("normalized_domain"), domain)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("token"), token)...)
 }
@@ -11,7 +11,9 @@ import (
 )
 
 func TestAccDomainResource(t *testing.T) {
-	domain := fmt.Sprintf("%s-test-terraform-provider.giautm.xyz", uuid.New())
+	label := testAccDomainLabel(uuid.New().String())
+	domain := label + testAccTestDomainSuffix
+	factories := testAccCassetteProviderFactories(t, label)
 
 	resource.Test(t, resource.TestCase{
 		ExternalProviders: map[string]resource.ExternalProvider{
@@ -21,7 +23,7 @@ func TestAccDomainResource(t *testing.T) {
 			},
 		},
 		PreCheck:                 func() { testAccPreCheck(t) },
-		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		ProtoV6ProviderFactories: factories,
 		Steps: []resource.TestStep{
 			{
 				Config: testAccDomainResourceConfig(domain),
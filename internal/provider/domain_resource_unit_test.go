@@ -0,0 +1,349 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"giautm.dev/googlesiteverification/internal/fake"
+)
+
+// testDomainResource returns a resource configured against pd.
+func testDomainResource(t *testing.T, pd apiClient) (*DomainResource, tfsdk.Schema) {
+	t.Helper()
+
+	r := &DomainResource{}
+	resp := &resource.ConfigureResponse{}
+	r.Configure(context.Background(), resource.ConfigureRequest{ProviderData: pd}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Configure() returned errors: %v", resp.Diagnostics)
+	}
+	schema, diags := r.GetSchema(context.Background())
+	if diags.HasError() {
+		t.Fatalf("GetSchema() returned errors: %v", diags)
+	}
+	return r, schema
+}
+
+// testDomainResourceValues returns the attributes of example.com with token.
+func testDomainResourceValues(id tftypes.Value, token string, timeouts tftypes.Value) map[string]tftypes.Value {
+	return map[string]tftypes.Value{
+		"id":                id,
+		"domain":            tftypes.NewValue(tftypes.String, "Example.com."),
+		"normalized_domain": tftypes.NewValue(tftypes.String, "example.com"),
+		"token":             tftypes.NewValue(tftypes.String, token),
+		"timeouts":          timeouts,
+	}
+}
+
+func wantDiagnostic(t *testing.T, diags diag.Diagnostics, want string) {
+	t.Helper()
+
+	if want == "" {
+		if diags.HasError() {
+			t.Fatalf("diagnostics = %v, want no errors", diags)
+		}
+		return
+	}
+	for _, d := range diags.Errors() {
+		if strings.Contains(d.Detail(), want) {
+			return
+		}
+	}
+	t.Fatalf("diagnostics = %v, want an error containing %q", diags, want)
+}
+
+func TestDomainResourceCreate(t *testing.T) {
+	for name, tt := range map[string]struct {
+		opts        []fake.Option
+		inject      func(*fake.Server)
+		timeouts    map[string]string
+		cancelAfter int
+		wantErr     string
+		wantSummary string
+		wantInserts int
+	}{
+		"verified": {
+			wantInserts: 1,
+		},
+		"retries while the token is not found": {
+			opts:        []fake.Option{fake.WithPublishedCheck(fake.After(2))},
+			wantInserts: 3,
+		},
+		"retries injected token not found errors": {
+			inject: func(s *fake.Server) {
+				s.Inject(fake.MethodInsert, http.StatusBadRequest, fake.MessageTokenNotFound, 3)
+			},
+			wantInserts: 4,
+		},
+		"does not retry other errors": {
+			inject: func(s *fake.Server) {
+				s.Inject(fake.MethodInsert, http.StatusBadRequest, "Invalid site.", 1)
+			},
+			wantErr:     "Invalid site.",
+			wantInserts: 1,
+		},
+		"timeout": {
+			opts:        []fake.Option{fake.WithPublishedCheck(fake.Never())},
+			timeouts:    map[string]string{"create": "50ms"},
+			wantErr:     "context deadline exceeded",
+			wantSummary: "Domain Not Verified: Token Not Found in DNS",
+		},
+		"cancellation": {
+			opts:        []fake.Option{fake.WithPublishedCheck(fake.Never())},
+			cancelAfter: 3,
+			wantErr:     "context canceled",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, pd := testFake(t, tt.opts...)
+			if tt.inject != nil {
+				tt.inject(s)
+			}
+			r, schema := testDomainResource(t, pd)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelAfter > 0 {
+				go func() {
+					for s.Calls(fake.MethodInsert) < tt.cancelAfter {
+						time.Sleep(testRetryInterval)
+					}
+					cancel()
+				}()
+			}
+
+			timeouts := tftypes.NewValue(schema.Type().TerraformType(ctx).(tftypes.Object).AttributeTypes["timeouts"], nil)
+			if tt.timeouts != nil {
+				timeouts = testTimeouts(schema, tt.timeouts)
+			}
+			plan := tfsdk.Plan{
+				Schema: schema,
+				Raw: testObject(t, schema, testDomainResourceValues(
+					tftypes.NewValue(tftypes.String, tftypes.UnknownValue), s.Token("example.com"), timeouts)),
+			}
+			resp := &resource.CreateResponse{State: tfsdk.State{Schema: schema, Raw: testObject(t, schema, nil)}}
+			r.Create(ctx, resource.CreateRequest{Plan: plan}, resp)
+
+			wantDiagnostic(t, resp.Diagnostics, tt.wantErr)
+			if tt.wantSummary != "" && resp.Diagnostics.Errors()[0].Summary() != tt.wantSummary {
+				t.Errorf("summary = %q, want %q", resp.Diagnostics.Errors()[0].Summary(), tt.wantSummary)
+			}
+			if tt.wantInserts > 0 && s.Calls(fake.MethodInsert) != tt.wantInserts {
+				t.Errorf("Calls(insert) = %d, want %d", s.Calls(fake.MethodInsert), tt.wantInserts)
+			}
+			if tt.wantErr != "" {
+				if s.Verified("example.com") {
+					t.Error("Verified() = true after a failed create")
+				}
+				return
+			}
+			var data DomainResourceModel
+			resp.Diagnostics.Append(resp.State.Get(ctx, &data)...)
+			wantDiagnostic(t, resp.Diagnostics, "")
+			if data.Id.Value != "dns://example.com" {
+				t.Errorf("id = %q, want dns://example.com", data.Id.Value)
+			}
+			if !s.Verified("example.com") {
+				t.Error("Verified() = false after create")
+			}
+		})
+	}
+}
+
+func TestDomainResourceRead(t *testing.T) {
+	for name, tt := range map[string]struct {
+		verified bool
+		wantErr  string
+	}{
+		"verified":     {verified: true},
+		"not verified": {wantErr: "Site dns://example.com not found."},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, pd := testFake(t)
+			if tt.verified {
+				s.Put("example.com")
+			}
+			r, schema := testDomainResource(t, pd)
+
+			state := tfsdk.State{
+				Schema: schema,
+				Raw: testObject(t, schema, testDomainResourceValues(
+					tftypes.NewValue(tftypes.String, "dns://example.com"), s.Token("example.com"),
+					tftypes.NewValue(schema.Type().TerraformType(ctx).(tftypes.Object).AttributeTypes["timeouts"], nil))),
+			}
+			resp := &resource.ReadResponse{State: state}
+			r.Read(ctx, resource.ReadRequest{State: state}, resp)
+
+			wantDiagnostic(t, resp.Diagnostics, tt.wantErr)
+			if tt.wantErr == "" && !resp.State.Raw.Equal(state.Raw) {
+				t.Errorf("Read() state = %v, want %v", resp.State.Raw, state.Raw)
+			}
+			if s.Calls(fake.MethodGet) != 1 {
+				t.Errorf("Calls(get) = %d, want 1", s.Calls(fake.MethodGet))
+			}
+		})
+	}
+}
+
+func TestDomainResourceReadUpgrade(t *testing.T) {
+	ctx := context.Background()
+	s, pd := testFake(t)
+	s.Put("example.com")
+	r, schema := testDomainResource(t, pd)
+
+	// State written before normalized_domain was added.
+	values := testDomainResourceValues(
+		tftypes.NewValue(tftypes.String, "dns://example.com"), s.Token("example.com"),
+		tftypes.NewValue(schema.Type().TerraformType(ctx).(tftypes.Object).AttributeTypes["timeouts"], nil))
+	delete(values, "normalized_domain")
+	state := tfsdk.State{Schema: schema, Raw: testObject(t, schema, values)}
+	resp := &resource.ReadResponse{State: state}
+	r.Read(ctx, resource.ReadRequest{State: state}, resp)
+
+	wantDiagnostic(t, resp.Diagnostics, "")
+	var data DomainResourceModel
+	if diags := resp.State.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.NormalizedDomain.Value != "example.com" {
+		t.Errorf("normalized_domain = %v, want example.com", data.NormalizedDomain)
+	}
+}
+
+func TestDomainResourceDelete(t *testing.T) {
+	for name, tt := range map[string]struct {
+		opts        []fake.Option
+		inject      func(*fake.Server)
+		timeouts    map[string]string
+		cancelAfter int
+		wantErr     string
+		wantSummary string
+		wantDeletes int
+	}{
+		"unverified": {
+			wantDeletes: 1,
+		},
+		"retries while the token exists": {
+			opts:        []fake.Option{fake.WithRemovedCheck(fake.After(2))},
+			wantDeletes: 3,
+		},
+		"retries injected token exists errors": {
+			inject: func(s *fake.Server) {
+				s.Inject(fake.MethodDelete, http.StatusBadRequest, fake.MessageTokenExists, 2)
+			},
+			wantDeletes: 3,
+		},
+		"does not retry other errors": {
+			inject: func(s *fake.Server) {
+				s.Inject(fake.MethodDelete, http.StatusForbidden, "You are not an owner of this site.", 1)
+			},
+			wantErr:     "You are not an owner of this site.",
+			wantSummary: "Credentials Lack Ownership of dns://example.com",
+			wantDeletes: 1,
+		},
+		"timeout": {
+			opts:     []fake.Option{fake.WithRemovedCheck(fake.Never())},
+			timeouts: map[string]string{"delete": "50ms"},
+			wantErr:  "context deadline exceeded",
+		},
+		"cancellation": {
+			opts:        []fake.Option{fake.WithRemovedCheck(fake.Never())},
+			cancelAfter: 3,
+			wantErr:     "context canceled",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, pd := testFake(t, tt.opts...)
+			s.Put("example.com")
+			if tt.inject != nil {
+				tt.inject(s)
+			}
+			r, schema := testDomainResource(t, pd)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelAfter > 0 {
+				go func() {
+					for s.Calls(fake.MethodDelete) < tt.cancelAfter {
+						time.Sleep(testRetryInterval)
+					}
+					cancel()
+				}()
+			}
+
+			timeouts := tftypes.NewValue(schema.Type().TerraformType(ctx).(tftypes.Object).AttributeTypes["timeouts"], nil)
+			if tt.timeouts != nil {
+				timeouts = testTimeouts(schema, tt.timeouts)
+			}
+			state := tfsdk.State{
+				Schema: schema,
+				Raw: testObject(t, schema, testDomainResourceValues(
+					tftypes.NewValue(tftypes.String, "dns://example.com"), s.Token("example.com"), timeouts)),
+			}
+			resp := &resource.DeleteResponse{State: state}
+			r.Delete(ctx, resource.DeleteRequest{State: state}, resp)
+
+			wantDiagnostic(t, resp.Diagnostics, tt.wantErr)
+			if tt.wantSummary != "" && resp.Diagnostics.Errors()[0].Summary() != tt.wantSummary {
+				t.Errorf("summary = %q, want %q", resp.Diagnostics.Errors()[0].Summary(), tt.wantSummary)
+			}
+			if tt.wantDeletes > 0 && s.Calls(fake.MethodDelete) != tt.wantDeletes {
+				t.Errorf("Calls(delete) = %d, want %d", s.Calls(fake.MethodDelete), tt.wantDeletes)
+			}
+			if got, want := s.Verified("example.com"), tt.wantErr != ""; got != want {
+				t.Errorf("Verified() = %t after delete, want %t", got, want)
+			}
+		})
+	}
+}
+
+func TestDomainResourceImportState(t *testing.T) {
+	for name, tt := range map[string]struct {
+		id       string
+		verified bool
+		wantErr  string
+	}{
+		"domain":         {id: "Example.com.", verified: true},
+		"id":             {id: "dns://example.com", verified: true},
+		"encoded id":     {id: "dns%3A%2F%2Fexample.com", verified: true},
+		"not verified":   {id: "example.com", wantErr: "Site dns://example.com not found."},
+		"url prefix":     {id: "https://example.com/", wantErr: "Only domain verifications can be imported"},
+		"invalid domain": {id: "dns://", wantErr: "Expected a domain"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, pd := testFake(t)
+			if tt.verified {
+				s.Put("example.com")
+			}
+			r, schema := testDomainResource(t, pd)
+
+			resp := &resource.ImportStateResponse{State: tfsdk.State{
+				Schema: schema,
+				Raw:    tftypes.NewValue(schema.Type().TerraformType(ctx), nil),
+			}}
+			r.ImportState(ctx, resource.ImportStateRequest{ID: tt.id}, resp)
+
+			wantDiagnostic(t, resp.Diagnostics, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+			var data DomainResourceModel
+			resp.Diagnostics.Append(resp.State.Get(ctx, &data)...)
+			wantDiagnostic(t, resp.Diagnostics, "")
+			if data.Id.Value != "dns://example.com" || data.Domain.Value != "example.com" ||
+				data.NormalizedDomain.Value != "example.com" || data.Token.Value != s.Token("example.com") {
+				t.Errorf("ImportState() state = %+v", data)
+			}
+		})
+	}
+}
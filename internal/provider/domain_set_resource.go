@@ -0,0 +1,663 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/time/rate"
+	"google.golang.org/api/siteverification/v1"
+
+	"giautm.dev/googlesiteverification/internal/apierror"
+	"giautm.dev/googlesiteverification/internal/domainname"
+	"giautm.dev/googlesiteverification/verifier"
+)
+
+type (
+	// DomainSetResource defines the resource implementation.
+	DomainSetResource struct {
+		client apiClient
+	}
+	// DomainSetResourceModel describes the resource data model.
+	DomainSetResourceModel struct {
+		ID                  types.String `tfsdk:"id"`
+		Domains             types.Map    `tfsdk:"domains"`
+		Concurrency         types.Int64  `tfsdk:"concurrency"`
+		RequestsPerSecond   types.Int64  `tfsdk:"requests_per_second"`
+		AllowPartialFailure types.Bool   `tfsdk:"allow_partial_failure"`
+		Verifications       types.Map    `tfsdk:"verifications"`
+		Timeouts            types.Object `tfsdk:"timeouts"`
+	}
+	// DomainSetVerification describes the verification of a single domain.
+	DomainSetVerification struct {
+		ID     types.String `tfsdk:"id"`
+		Token  types.String `tfsdk:"token"`
+		Status types.String `tfsdk:"status"`
+	}
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var (
+	_ resource.Resource                   = &DomainSetResource{}
+	_ resource.ResourceWithModifyPlan     = &DomainSetResource{}
+	_ resource.ResourceWithValidateConfig = &DomainSetResource{}
+
+	domainSetVerificationType = types.ObjectType{
+		AttrTypes: map[string]attr.Type{
+			"id":     types.StringType,
+			"token":  types.StringType,
+			"status": types.StringType,
+		},
+	}
+)
+
+const (
+	statusVerified = "verified"
+	statusFailed   = "failed"
+	statusMissing  = "missing"
+
+	defaultRequestsPerSecond = 5
+)
+
+func NewDomainSetResource() resource.Resource {
+	return &DomainSetResource{}
+}
+
+func (r *DomainSetResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_domain_set"
+}
+
+func (r *DomainSetResource) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		MarkdownDescription: "Manages DNS verifications for many domains. Adding or removing domains only verifies or unverifies the changed domains. Domains that cannot be verified before the timeout fail the apply, unless `allow_partial_failure` is set, and are recorded with a `failed` status and verified again on the next apply. With only the `siteverification.verify_only` provider scope, refresh trusts the state and removed domains stay verified.",
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				Computed:            true,
+				MarkdownDescription: "The id of the domain set.",
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.UseStateForUnknown(),
+				},
+				Type: types.StringType,
+			},
+			"domains": {
+				MarkdownDescription: "The domains you want to verify, mapped to the token you got from data.googlesiteverification_domains. A changed token verifies that domain again.",
+				Required:            true,
+				Type:                types.MapType{ElemType: types.StringType},
+			},
+			"concurrency": {
+				MarkdownDescription: fmt.Sprintf("The maximum number of API requests in flight at the same time. Domains waiting to retry do not hold back the others. Defaults to `%d`.", defaultConcurrency),
+				Optional:            true,
+				Type:                types.Int64Type,
+			},
+			"requests_per_second": {
+				MarkdownDescription: fmt.Sprintf("The maximum number of API requests per second shared by all domains, including retries, over a whole create, read, update or delete. Defaults to `%d`.", defaultRequestsPerSecond),
+				Optional:            true,
+				Type:                types.Int64Type,
+			},
+			"allow_partial_failure": {
+				MarkdownDescription: "Report domains that cannot be verified as a warning instead of an error, so that the apply succeeds with them in a `failed` status. By default such domains fail the apply; when that happens on create, Terraform marks the set as tainted and replaces it, unverifying its verified domains, on the next apply. Defaults to `false`.",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"verifications": {
+				MarkdownDescription: "The verification of each domain, keyed by domain as configured in `domains`.",
+				Computed:            true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{
+					resource.UseStateForUnknown(),
+				},
+				Attributes: tfsdk.MapNestedAttributes(map[string]tfsdk.Attribute{
+					"id": {
+						MarkdownDescription: "The id of the verification.",
+						Type:                types.StringType,
+						Computed:            true,
+					},
+					"token": {
+						MarkdownDescription: "The token the domain was verified with.",
+						Type:                types.StringType,
+						Computed:            true,
+					},
+					"status": {
+						MarkdownDescription: fmt.Sprintf("The status of the verification: `%s`, `%s` when the last attempt failed, or `%s` when it was removed outside of Terraform. Domains that are not `%s` are verified again on the next apply.", statusVerified, statusFailed, statusMissing, statusVerified),
+						Type:                types.StringType,
+						Computed:            true,
+					},
+				}),
+			},
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Read:   true,
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}, nil
+}
+
+func (r *DomainSetResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(apiClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected apiClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	r.client = data
+}
+
+func (r *DomainSetResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data DomainSetResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !data.Domains.IsNull() && !data.Domains.IsUnknown() {
+		seen := map[string]string{}
+		keys := make([]string, 0, len(data.Domains.Elems))
+		for domain := range data.Domains.Elems {
+			keys = append(keys, domain)
+		}
+		sort.Strings(keys)
+		for _, domain := range keys {
+			normalized, err := domainname.Normalize(domain)
+			if err != nil {
+				resp.Diagnostics.AddAttributeError(path.Root("domains").AtMapKey(domain),
+					"Invalid Domain",
+					fmt.Sprintf("Unable to use %q as a domain name: %s", domain, err),
+				)
+				continue
+			}
+			if other, ok := seen[normalized]; ok {
+				resp.Diagnostics.AddAttributeError(path.Root("domains").AtMapKey(domain),
+					"Duplicate Domain",
+					fmt.Sprintf("The domains %q and %q are the same domain.", other, domain),
+				)
+			}
+			seen[normalized] = domain
+		}
+	}
+	for name, value := range map[string]types.Int64{
+		"concurrency":         data.Concurrency,
+		"requests_per_second": data.RequestsPerSecond,
+	} {
+		if !value.IsNull() && !value.IsUnknown() && value.Value < 1 {
+			resp.Diagnostics.AddAttributeError(path.Root(name),
+				"Invalid Attribute Value",
+				fmt.Sprintf("The %s must be at least 1, got: %d.", name, value.Value),
+			)
+		}
+	}
+}
+
+func (r *DomainSetResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to reconcile on create or destroy.
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan, state DomainSetResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	verifications, diags := verificationsFromMap(ctx, state.Verifications)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Verifications are only kept from state when every configured domain is
+	// verified with its configured token and nothing is left to unverify.
+	stale := plan.Domains.IsUnknown() || len(verifications) != len(plan.Domains.Elems)
+	for domain, v := range verifications {
+		token, ok := plan.Domains.Elems[domain].(types.String)
+		if !ok || token.IsUnknown() || token.Value != v.Token.Value || v.Status.Value != statusVerified {
+			stale = true
+			break
+		}
+	}
+	if stale {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("verifications"), types.Map{
+			ElemType: domainSetVerificationType,
+			Unknown:  true,
+		})...)
+	}
+}
+
+func (r *DomainSetResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data DomainSetResourceModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	createTimeout := timeouts.Create(ctx, data.Timeouts, defaultTimeout)
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	domains, diags := domainsFromMap(ctx, data.Domains)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ID = types.String{Value: domainsID(sortedKeys(domains))}
+
+	verifications := r.reconcile(ctx, &data, domains, nil, &resp.Diagnostics)
+
+	// Write logs using the tflog package
+	// Documentation: https://terraform.io/plugin/log
+	tflog.Trace(ctx, "created a resource")
+
+	// Save data into Terraform state, including partial progress on errors
+	resp.Diagnostics.Append(data.setVerifications(ctx, verifications)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *DomainSetResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data DomainSetResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	verifications, diags := verificationsFromMap(ctx, data.Verifications)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !r.client.Capabilities().read {
+		resp.Diagnostics.AddWarning("Verifications Not Refreshed",
+			"The provider scopes do not permit reading verifications, so the domains are assumed to be still verified.")
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	readTimeout := timeouts.Read(ctx, data.Timeouts, defaultTimeout)
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	op := r.newOperation(&data)
+	var mu sync.Mutex
+	errs := op.forEach(ctx, sortedKeys(verifications), func(ctx context.Context, domain string) error {
+		mu.Lock()
+		v := verifications[domain]
+		mu.Unlock()
+		if v.Status.Value != statusVerified {
+			return nil
+		}
+		if err := wait(ctx, op.limiter); err != nil {
+			return err
+		}
+		_, err := op.api.Get(ctx, v.ID.Value)
+		if apierror.Classify(err) == apierror.NotFound {
+			tflog.Warn(ctx, "Verification was removed outside of Terraform", map[string]interface{}{
+				"domain": domain,
+			})
+			v.Status = types.String{Value: statusMissing}
+			mu.Lock()
+			verifications[domain] = v
+			mu.Unlock()
+			return nil
+		}
+		return err
+	})
+	if len(errs) > 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("domains"), domainErrorsSummary(errs, "Client Error"), domainErrors("read verification", errs, len(verifications)))
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(data.setVerifications(ctx, verifications)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *DomainSetResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data, state DomainSetResourceModel
+
+	// Read Terraform plan and prior state data into the models
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	updateTimeout := timeouts.Update(ctx, data.Timeouts, defaultTimeout)
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	domains, diags := domainsFromMap(ctx, data.Domains)
+	resp.Diagnostics.Append(diags...)
+	previous, diags := verificationsFromMap(ctx, state.Verifications)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	verifications := r.reconcile(ctx, &data, domains, previous, &resp.Diagnostics)
+
+	// Save data into Terraform state, including partial progress on errors
+	resp.Diagnostics.Append(data.setVerifications(ctx, verifications)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *DomainSetResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data DomainSetResourceModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	deleteTimeout := timeouts.Delete(ctx, data.Timeouts, defaultTimeout)
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
+	verifications, diags := verificationsFromMap(ctx, data.Verifications)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var removed []string
+	for domain, v := range verifications {
+		if v.Status.Value == statusVerified {
+			removed = append(removed, domain)
+		}
+	}
+	sort.Strings(removed)
+	if !r.client.Capabilities().unverify {
+		if len(removed) > 0 {
+			resp.Diagnostics.AddWarning("Verifications Not Deleted", notUnverified(removed))
+		}
+		return
+	}
+	op := r.newOperation(&data)
+	errs := op.forEach(ctx, removed, func(ctx context.Context, domain string) error {
+		return op.verifier().Unverify(ctx, verifications[domain].ID.Value)
+	})
+	if len(errs) > 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("domains"), domainErrorsSummary(errs, "Client Error"), domainErrors("delete verification", errs, len(removed)))
+	}
+}
+
+// reconcile unverifies the domains that were removed from the set, then
+// verifies the domains that were added, whose token changed, or that are not
+// verified yet. Domains are compared by their normalized name, so that a key
+// that only changes in case or trailing dot keeps its verification. Errors are
+// collected into a single diagnostic per operation and the returned
+// verifications reflect the partial progress.
+func (r *DomainSetResource) reconcile(ctx context.Context, data *DomainSetResourceModel, domains map[string]string, previous map[string]DomainSetVerification, diags *diag.Diagnostics) map[string]DomainSetVerification {
+	configured := make(map[string]string, len(domains))
+	for domain := range domains {
+		configured[normalizedKey(domain)] = domain
+	}
+	verifications := make(map[string]DomainSetVerification, len(domains))
+	var added, removed []string
+	for domain, v := range previous {
+		key, ok := configured[normalizedKey(domain)]
+		if !ok {
+			if v.Status.Value == statusVerified {
+				removed = append(removed, domain)
+			}
+			continue
+		}
+		verifications[key] = v
+	}
+	for domain, token := range domains {
+		v, ok := verifications[domain]
+		if !ok || v.Token.Value != token || v.Status.Value != statusVerified {
+			added = append(added, domain)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	if !r.client.Capabilities().unverify && len(removed) > 0 {
+		diags.AddWarning("Verifications Not Deleted", notUnverified(removed))
+		removed = nil
+	}
+
+	// Both phases share the limits of the operation.
+	op := r.newOperation(data)
+	var mu sync.Mutex
+	errs := op.forEach(ctx, removed, func(ctx context.Context, domain string) error {
+		mu.Lock()
+		v := previous[domain]
+		mu.Unlock()
+		if err := op.verifier().Unverify(ctx, v.ID.Value); err != nil {
+			// Keep the verification in state so the next apply tries again.
+			mu.Lock()
+			verifications[domain] = v
+			mu.Unlock()
+			return err
+		}
+		return nil
+	})
+	if len(errs) > 0 {
+		diags.AddAttributeError(path.Root("domains"), domainErrorsSummary(errs, "Client Error"), domainErrors("delete verification", errs, len(removed)))
+	}
+
+	errs = op.forEach(ctx, added, func(ctx context.Context, domain string) error {
+		normalized, err := domainname.Normalize(domain)
+		if err != nil {
+			return err
+		}
+		id, err := op.verifier().Verify(ctx, normalized)
+		v := DomainSetVerification{
+			ID:     types.String{Value: id},
+			Token:  types.String{Value: domains[domain]},
+			Status: types.String{Value: statusVerified},
+		}
+		if err != nil {
+			v.ID = types.String{Null: true}
+			v.Status = types.String{Value: statusFailed}
+		}
+		mu.Lock()
+		verifications[domain] = v
+		mu.Unlock()
+		return err
+	})
+	if len(errs) > 0 {
+		summary := domainErrorsSummary(errs, "Domains Not Verified")
+		detail := domainErrors("create verification", errs, len(added)) + "\n\nThese domains are verified again on the next apply."
+		if data.AllowPartialFailure.Value {
+			diags.AddAttributeWarning(path.Root("domains"), summary, detail)
+		} else {
+			diags.AddAttributeError(path.Root("domains"), summary,
+				detail+" Set allow_partial_failure to report them as a warning instead.")
+		}
+	}
+
+	tflog.Debug(ctx, "reconciled domain set", map[string]interface{}{
+		"added":   len(added),
+		"removed": len(removed),
+	})
+	return verifications
+}
+
+// setOperation is the API client of a single create, read, update or delete.
+// Every domain of the operation shares its rate limit and concurrency limit.
+type setOperation struct {
+	api      verifier.API
+	limiter  *rate.Limiter
+	interval time.Duration
+}
+
+func (r *DomainSetResource) newOperation(data *DomainSetResourceModel) *setOperation {
+	concurrency := defaultConcurrency
+	if !data.Concurrency.IsNull() && !data.Concurrency.IsUnknown() {
+		concurrency = int(data.Concurrency.Value)
+	}
+	rps := defaultRequestsPerSecond
+	if !data.RequestsPerSecond.IsNull() && !data.RequestsPerSecond.IsUnknown() {
+		rps = int(data.RequestsPerSecond.Value)
+	}
+	return &setOperation{
+		api:      &limitedAPI{API: r.client, sem: make(chan struct{}, concurrency)},
+		limiter:  rate.NewLimiter(rate.Limit(rps), 1),
+		interval: r.client.RetryInterval(),
+	}
+}
+
+func (op *setOperation) verifier() *verifier.Verifier {
+	return newVerifier(op.api, op.limiter, op.interval)
+}
+
+// forEach calls fn for every domain at the same time, and returns the errors
+// by domain. The concurrency limit applies to the API calls made by fn.
+func (op *setOperation) forEach(ctx context.Context, domains []string, fn func(context.Context, string) error) map[string]error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs = map[string]error{}
+	)
+	for _, domain := range domains {
+		wg.Add(1)
+		go func(domain string) {
+			defer wg.Done()
+			if err := fn(ctx, domain); err != nil {
+				mu.Lock()
+				errs[domain] = err
+				mu.Unlock()
+			}
+		}(domain)
+	}
+	wg.Wait()
+	return errs
+}
+
+// limitedAPI bounds the calls in flight with a semaphore. A slot is only held
+// during a call, so that domains waiting to retry do not block the others.
+type limitedAPI struct {
+	verifier.API
+	sem chan struct{}
+}
+
+func (a *limitedAPI) acquire(ctx context.Context) error {
+	select {
+	case a.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *limitedAPI) release() { <-a.sem }
+
+func (a *limitedAPI) GetToken(ctx context.Context, domain string) (string, error) {
+	if err := a.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer a.release()
+	return a.API.GetToken(ctx, domain)
+}
+
+func (a *limitedAPI) Insert(ctx context.Context, domain string) (*siteverification.SiteVerificationWebResourceResource, error) {
+	if err := a.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.release()
+	return a.API.Insert(ctx, domain)
+}
+
+func (a *limitedAPI) Get(ctx context.Context, id string) (*siteverification.SiteVerificationWebResourceResource, error) {
+	if err := a.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.release()
+	return a.API.Get(ctx, id)
+}
+
+func (a *limitedAPI) List(ctx context.Context) ([]*siteverification.SiteVerificationWebResourceResource, error) {
+	if err := a.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.release()
+	return a.API.List(ctx)
+}
+
+func (a *limitedAPI) Update(ctx context.Context, id string, res *siteverification.SiteVerificationWebResourceResource) (*siteverification.SiteVerificationWebResourceResource, error) {
+	if err := a.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.release()
+	return a.API.Update(ctx, id, res)
+}
+
+func (a *limitedAPI) Delete(ctx context.Context, id string) error {
+	if err := a.acquire(ctx); err != nil {
+		return err
+	}
+	defer a.release()
+	return a.API.Delete(ctx, id)
+}
+
+func (m *DomainSetResourceModel) setVerifications(ctx context.Context, verifications map[string]DomainSetVerification) diag.Diagnostics {
+	return tfsdk.ValueFrom(ctx, verifications, types.MapType{ElemType: domainSetVerificationType}, &m.Verifications)
+}
+
+func domainsFromMap(ctx context.Context, m types.Map) (map[string]string, diag.Diagnostics) {
+	domains := map[string]string{}
+	diags := m.ElementsAs(ctx, &domains, false)
+	return domains, diags
+}
+
+func verificationsFromMap(ctx context.Context, m types.Map) (map[string]DomainSetVerification, diag.Diagnostics) {
+	verifications := map[string]DomainSetVerification{}
+	if m.IsNull() || m.IsUnknown() {
+		return verifications, nil
+	}
+	diags := m.ElementsAs(ctx, &verifications, false)
+	return verifications, diags
+}
+
+// notUnverified explains that domains were removed from state without being
+// unverified because of the provider scopes.
+func notUnverified(domains []string) string {
+	return fmt.Sprintf("The provider scopes do not permit unverifying domains, so %s are removed from state but stay verified. Remove them in Search Console or with the https://www.googleapis.com/auth/siteverification scope.", strings.Join(domains, ", "))
+}
+
+// normalizedKey returns the normalized form of a configured domain, or the
+// domain itself when it is invalid.
+func normalizedKey(domain string) string {
+	if normalized, err := domainname.Normalize(domain); err == nil {
+		return normalized
+	}
+	return domain
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,85 @@
+package provider_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDomainSetResource(t *testing.T) {
+	// Both domains share the label, so that the cassette replaces it in both.
+	label := testAccDomainLabel(uuid.New().String())
+	first := label + testAccTestDomainSuffix
+	second := "www." + first
+	factories := testAccCassetteProviderFactories(t, label)
+
+	resource.Test(t, resource.TestCase{
+		ExternalProviders: map[string]resource.ExternalProvider{
+			"cloudflare": {
+				Source:            "cloudflare/cloudflare",
+				VersionConstraint: "3.33.1",
+			},
+		},
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: factories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDomainSetResourceConfig(first),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("googlesiteverification_domain_set.example", "verifications.%", "1"),
+					resource.TestCheckResourceAttr("googlesiteverification_domain_set.example", fmt.Sprintf("verifications.%s.status", first), "verified"),
+					resource.TestCheckResourceAttr("googlesiteverification_domain_set.example", fmt.Sprintf("verifications.%s.id", first), "dns://"+first),
+				),
+			},
+			// Adding a domain verifies it in place
+			{
+				Config: testAccDomainSetResourceConfig(first, second),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("googlesiteverification_domain_set.example", "verifications.%", "2"),
+					resource.TestCheckResourceAttr("googlesiteverification_domain_set.example", fmt.Sprintf("verifications.%s.status", second), "verified"),
+				),
+			},
+		},
+	})
+}
+
+func testAccDomainSetResourceConfig(domains ...string) string {
+	return fmt.Sprintf(`
+	data "googlesiteverification_domains" "example" {
+		domains = [%[1]s]
+	}
+	resource "cloudflare_record" "verification" {
+		for_each = data.googlesiteverification_domains.example.records
+
+		zone_id = %[2]q
+		name    = each.value.record_name
+		value   = each.value.record_value
+		type    = each.value.record_type
+	}
+	resource "googlesiteverification_domain_set" "example" {
+		domains = {
+			for domain, record in data.googlesiteverification_domains.example.records : domain => record.record_value
+		}
+		concurrency = 2
+		depends_on = [
+			cloudflare_record.verification,
+		]
+		timeouts {
+			create = "5m"
+			update = "5m"
+			delete = "15m"
+		}
+	}`, testAccQuoteList(domains), os.Getenv("CLOUDFLARE_ZONE_ID"))
+}
+
+func testAccQuoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return strings.Join(quoted, ", ")
+}
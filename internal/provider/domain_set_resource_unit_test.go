@@ -0,0 +1,346 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"google.golang.org/api/siteverification/v1"
+
+	"giautm.dev/googlesiteverification/internal/fake"
+	"giautm.dev/googlesiteverification/internal/siteid"
+	"giautm.dev/googlesiteverification/verifier"
+)
+
+// testDomainSetResource returns a domain set resource configured against pd.
+func testDomainSetResource(t *testing.T, pd *providerClient) (*DomainSetResource, tfsdk.Schema) {
+	t.Helper()
+
+	r := &DomainSetResource{}
+	resp := &resource.ConfigureResponse{}
+	r.Configure(context.Background(), resource.ConfigureRequest{ProviderData: pd}, resp)
+	wantDiagnostic(t, resp.Diagnostics, "")
+	schema, diags := r.GetSchema(context.Background())
+	wantDiagnostic(t, diags, "")
+	return r, schema
+}
+
+// testDomainSetValues returns the attributes of a domain set of domains
+// mapped to their token, with the given verifications and extra attributes.
+// Null verifications are unknown, as in the plan of a create.
+func testDomainSetValues(schema tfsdk.Schema, domains map[string]string, verifications map[string]DomainSetVerification, extra map[string]tftypes.Value) map[string]tftypes.Value {
+	typ := schema.Type().TerraformType(context.Background()).(tftypes.Object)
+	tokens := map[string]tftypes.Value{}
+	for domain, token := range domains {
+		tokens[domain] = tftypes.NewValue(tftypes.String, token)
+	}
+	verificationsType := typ.AttributeTypes["verifications"].(tftypes.Map)
+	objectType := verificationsType.ElementType.(tftypes.Object)
+	var verificationsValue tftypes.Value
+	if verifications == nil {
+		verificationsValue = tftypes.NewValue(verificationsType, tftypes.UnknownValue)
+	} else {
+		elems := map[string]tftypes.Value{}
+		for domain, v := range verifications {
+			id := tftypes.NewValue(tftypes.String, nil)
+			if !v.ID.IsNull() {
+				id = tftypes.NewValue(tftypes.String, v.ID.Value)
+			}
+			elems[domain] = tftypes.NewValue(objectType, map[string]tftypes.Value{
+				"id":     id,
+				"token":  tftypes.NewValue(tftypes.String, v.Token.Value),
+				"status": tftypes.NewValue(tftypes.String, v.Status.Value),
+			})
+		}
+		verificationsValue = tftypes.NewValue(verificationsType, elems)
+	}
+	values := map[string]tftypes.Value{
+		"id":            tftypes.NewValue(tftypes.String, "domain-set"),
+		"domains":       tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, tokens),
+		"verifications": verificationsValue,
+		"timeouts":      testTimeouts(schema, map[string]string{"create": "300ms", "update": "300ms"}),
+		// Far above the default, so that the rate limit never outlasts the
+		// timeouts unless a test sets its own.
+		"requests_per_second": tftypes.NewValue(tftypes.Number, 1000),
+	}
+	for name, value := range extra {
+		values[name] = value
+	}
+	return values
+}
+
+func testVerified(domain, token string) DomainSetVerification {
+	v := DomainSetVerification{}
+	v.ID.Value = "dns://" + domain
+	v.Token.Value = token
+	v.Status.Value = statusVerified
+	return v
+}
+
+// testDomainSetState returns the verifications saved in state.
+func testDomainSetState(t *testing.T, state tfsdk.State) map[string]DomainSetVerification {
+	t.Helper()
+
+	var data DomainSetResourceModel
+	diags := state.Get(context.Background(), &data)
+	wantDiagnostic(t, diags, "")
+	verifications, diags := verificationsFromMap(context.Background(), data.Verifications)
+	wantDiagnostic(t, diags, "")
+	return verifications
+}
+
+// notPublished reports the token of domain as never published.
+func notPublished(domain string) fake.Check {
+	return func(ctx context.Context, site siteid.Site, token string) (bool, error) {
+		return site.Identifier != domain, nil
+	}
+}
+
+func hasWarning(diags diag.Diagnostics, want string) bool {
+	for _, d := range diags.Warnings() {
+		if strings.Contains(d.Detail(), want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDomainSetResourceCreate(t *testing.T) {
+	for name, tt := range map[string]struct {
+		extra       map[string]tftypes.Value
+		wantErr     string
+		wantWarning string
+	}{
+		"error": {
+			wantErr: "b.example.com",
+		},
+		"allow partial failure": {
+			extra:       map[string]tftypes.Value{"allow_partial_failure": tftypes.NewValue(tftypes.Bool, true)},
+			wantWarning: "b.example.com",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, pd := testFake(t, fake.WithPublishedCheck(notPublished("b.example.com")))
+			r, schema := testDomainSetResource(t, pd)
+			domains := map[string]string{"a.example.com": "a", "b.example.com": "b", "c.example.com": "c"}
+
+			plan := tfsdk.Plan{Schema: schema, Raw: testObject(t, schema, testDomainSetValues(schema, domains, nil, tt.extra))}
+			resp := &resource.CreateResponse{State: tfsdk.State{Schema: schema, Raw: testObject(t, schema, nil)}}
+			r.Create(ctx, resource.CreateRequest{Plan: plan}, resp)
+
+			wantDiagnostic(t, resp.Diagnostics, tt.wantErr)
+			if tt.wantWarning != "" && !hasWarning(resp.Diagnostics, tt.wantWarning) {
+				t.Errorf("diagnostics = %v, want a warning containing %q", resp.Diagnostics, tt.wantWarning)
+			}
+			// The verified domains are saved even when the create fails.
+			got := testDomainSetState(t, resp.State)
+			for _, domain := range []string{"a.example.com", "c.example.com"} {
+				if got[domain].Status.Value != statusVerified || !s.Verified(domain) {
+					t.Errorf("%s = %+v, verified %t", domain, got[domain], s.Verified(domain))
+				}
+			}
+			if b := got["b.example.com"]; b.Status.Value != statusFailed || !b.ID.IsNull() {
+				t.Errorf("b.example.com = %+v, want failed", b)
+			}
+		})
+	}
+}
+
+func TestDomainSetResourceUpdate(t *testing.T) {
+	for name, tt := range map[string]struct {
+		domains     map[string]string
+		wantInserts int
+		wantKeys    []string
+	}{
+		"retry failed": {
+			domains:     map[string]string{"a.example.com": "a", "b.example.com": "b"},
+			wantInserts: 1,
+			wantKeys:    []string{"a.example.com", "b.example.com"},
+		},
+		"renamed key": {
+			domains:     map[string]string{"A.Example.com.": "a", "b.example.com": "b"},
+			wantInserts: 1,
+			wantKeys:    []string{"A.Example.com.", "b.example.com"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, pd := testFake(t)
+			s.Put("a.example.com")
+			r, schema := testDomainSetResource(t, pd)
+			previous := map[string]DomainSetVerification{
+				"a.example.com": testVerified("a.example.com", "a"),
+				"b.example.com": {},
+			}
+			failed := previous["b.example.com"]
+			failed.ID.Null = true
+			failed.Token.Value = "b"
+			failed.Status.Value = statusFailed
+			previous["b.example.com"] = failed
+
+			state := tfsdk.State{Schema: schema, Raw: testObject(t, schema, testDomainSetValues(schema, map[string]string{"a.example.com": "a", "b.example.com": "b"}, previous, nil))}
+			plan := tfsdk.Plan{Schema: schema, Raw: testObject(t, schema, testDomainSetValues(schema, tt.domains, nil, nil))}
+			resp := &resource.UpdateResponse{State: state}
+			r.Update(ctx, resource.UpdateRequest{Plan: plan, State: state}, resp)
+
+			wantDiagnostic(t, resp.Diagnostics, "")
+			if s.Calls(fake.MethodInsert) != tt.wantInserts || s.Calls(fake.MethodDelete) != 0 {
+				t.Errorf("inserts = %d, deletes = %d, want %d inserts only", s.Calls(fake.MethodInsert), s.Calls(fake.MethodDelete), tt.wantInserts)
+			}
+			got := testDomainSetState(t, resp.State)
+			if len(got) != len(tt.wantKeys) {
+				t.Errorf("verifications = %+v, want %v", got, tt.wantKeys)
+			}
+			for _, key := range tt.wantKeys {
+				if got[key].Status.Value != statusVerified {
+					t.Errorf("%s = %+v, want verified", key, got[key])
+				}
+			}
+		})
+	}
+}
+
+func TestDomainSetResourceUnverifyFailure(t *testing.T) {
+	ctx := context.Background()
+	s, pd := testFake(t)
+	s.Put("a.example.com")
+	s.Put("b.example.com")
+	s.Inject(fake.MethodDelete, http.StatusForbidden, "You are not an owner of this site.", 0)
+	r, schema := testDomainSetResource(t, pd)
+	previous := map[string]DomainSetVerification{
+		"a.example.com": testVerified("a.example.com", "a"),
+		"b.example.com": testVerified("b.example.com", "b"),
+	}
+
+	state := tfsdk.State{Schema: schema, Raw: testObject(t, schema, testDomainSetValues(schema, map[string]string{"a.example.com": "a", "b.example.com": "b"}, previous, nil))}
+	plan := tfsdk.Plan{Schema: schema, Raw: testObject(t, schema, testDomainSetValues(schema, map[string]string{"a.example.com": "a"}, nil, nil))}
+	resp := &resource.UpdateResponse{State: state}
+	r.Update(ctx, resource.UpdateRequest{Plan: plan, State: state}, resp)
+
+	wantDiagnostic(t, resp.Diagnostics, "b.example.com")
+	got := testDomainSetState(t, resp.State)
+	if got["b.example.com"] != previous["b.example.com"] || got["a.example.com"] != previous["a.example.com"] {
+		t.Errorf("verifications = %+v, want b.example.com kept in state", got)
+	}
+}
+
+// countingAPI records the most GetToken and Insert calls in flight at the
+// same time.
+type countingAPI struct {
+	verifier.API
+	mu       sync.Mutex
+	inFlight int
+	max      int
+}
+
+// track counts a call in flight for 5ms, and returns the function ending it.
+func (a *countingAPI) track() func() {
+	a.mu.Lock()
+	a.inFlight++
+	if a.inFlight > a.max {
+		a.max = a.inFlight
+	}
+	a.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	return func() {
+		a.mu.Lock()
+		a.inFlight--
+		a.mu.Unlock()
+	}
+}
+
+func (a *countingAPI) GetToken(ctx context.Context, domain string) (string, error) {
+	defer a.track()()
+	return a.API.GetToken(ctx, domain)
+}
+
+func (a *countingAPI) Insert(ctx context.Context, domain string) (*siteverification.SiteVerificationWebResourceResource, error) {
+	defer a.track()()
+	return a.API.Insert(ctx, domain)
+}
+
+func TestDomainSetResourceLimits(t *testing.T) {
+	ctx := context.Background()
+	domains := map[string]string{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		domains[name+".example.com"] = name
+	}
+
+	t.Run("concurrency", func(t *testing.T) {
+		_, pd := testFake(t)
+		api := &countingAPI{API: pd.API}
+		pd.API = api
+		r, schema := testDomainSetResource(t, pd)
+		plan := tfsdk.Plan{Schema: schema, Raw: testObject(t, schema, testDomainSetValues(schema, domains, nil, map[string]tftypes.Value{
+			"concurrency": tftypes.NewValue(tftypes.Number, 2),
+		}))}
+		resp := &resource.CreateResponse{State: tfsdk.State{Schema: schema, Raw: testObject(t, schema, nil)}}
+		r.Create(ctx, resource.CreateRequest{Plan: plan}, resp)
+
+		wantDiagnostic(t, resp.Diagnostics, "")
+		if api.max > 2 {
+			t.Errorf("%d inserts in flight, want at most 2", api.max)
+		}
+	})
+
+	t.Run("retries release the slot", func(t *testing.T) {
+		s, pd := testFake(t, fake.WithPublishedCheck(notPublished("a.example.com")))
+		r, schema := testDomainSetResource(t, pd)
+		plan := tfsdk.Plan{Schema: schema, Raw: testObject(t, schema, testDomainSetValues(schema, domains, nil, map[string]tftypes.Value{
+			"concurrency":           tftypes.NewValue(tftypes.Number, 1),
+			"allow_partial_failure": tftypes.NewValue(tftypes.Bool, true),
+		}))}
+		resp := &resource.CreateResponse{State: tfsdk.State{Schema: schema, Raw: testObject(t, schema, nil)}}
+		r.Create(ctx, resource.CreateRequest{Plan: plan}, resp)
+
+		wantDiagnostic(t, resp.Diagnostics, "")
+		for domain := range domains {
+			if domain != "a.example.com" && !s.Verified(domain) {
+				t.Errorf("%s was not verified while a.example.com was retried", domain)
+			}
+		}
+	})
+
+	t.Run("requests per second", func(t *testing.T) {
+		// Unverifying three domains and verifying three others shares one
+		// budget of 20 requests per second: six requests take 250ms.
+		s, pd := testFake(t)
+		previous := map[string]DomainSetVerification{}
+		planned := map[string]string{}
+		for i, domain := range sortedKeys(domains) {
+			if i < 3 {
+				s.Put(domain)
+				previous[domain] = testVerified(domain, domains[domain])
+			} else {
+				planned[domain] = domains[domain]
+			}
+		}
+		r, schema := testDomainSetResource(t, pd)
+		extra := map[string]tftypes.Value{"requests_per_second": tftypes.NewValue(tftypes.Number, 20)}
+		prior := map[string]string{}
+		for domain, v := range previous {
+			prior[domain] = v.Token.Value
+		}
+		state := tfsdk.State{Schema: schema, Raw: testObject(t, schema, testDomainSetValues(schema, prior, previous, extra))}
+		plan := tfsdk.Plan{Schema: schema, Raw: testObject(t, schema, testDomainSetValues(schema, planned, nil, extra))}
+		resp := &resource.UpdateResponse{State: state}
+		start := time.Now()
+		r.Update(ctx, resource.UpdateRequest{Plan: plan, State: state}, resp)
+
+		wantDiagnostic(t, resp.Diagnostics, "")
+		if elapsed := time.Since(start); elapsed < 240*time.Millisecond {
+			t.Errorf("update took %s, want at least 250ms at 20 requests per second", elapsed)
+		}
+		if s.Calls(fake.MethodDelete) != 3 || s.Calls(fake.MethodInsert) != 3 {
+			t.Errorf("deletes = %d, inserts = %d, want 3 each", s.Calls(fake.MethodDelete), s.Calls(fake.MethodInsert))
+		}
+	})
+}
@@ -0,0 +1,254 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"giautm.dev/googlesiteverification/internal/domainname"
+)
+
+type (
+	// DomainsDataSource defines the data source implementation.
+	DomainsDataSource struct {
+		client apiClient
+	}
+	// DomainsDataSourceModel describes the data source data model.
+	DomainsDataSourceModel struct {
+		ID          types.String                       `tfsdk:"id"`
+		Domains     []string                           `tfsdk:"domains"`
+		Concurrency types.Int64                        `tfsdk:"concurrency"`
+		Records     map[string]DomainsDataSourceRecord `tfsdk:"records"`
+		Timeouts    types.Object                       `tfsdk:"timeouts"`
+	}
+	// DomainsDataSourceRecord describes the record of a single domain.
+	DomainsDataSourceRecord struct {
+		RecordType  types.String `tfsdk:"record_type"`
+		RecordName  types.String `tfsdk:"record_name"`
+		RecordValue types.String `tfsdk:"record_value"`
+	}
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var (
+	_ datasource.DataSource                   = &DomainsDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &DomainsDataSource{}
+)
+
+const defaultConcurrency = 8
+
+func NewDomainsDataSource() datasource.DataSource {
+	return &DomainsDataSource{}
+}
+
+func (d *DomainsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_domains"
+}
+
+func (d *DomainsDataSource) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		MarkdownDescription: "The Domains data source provides tokens for verifying the ownership of many domains in one read.",
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "A hash of the requested domains.",
+				Type:                types.StringType,
+				Computed:            true,
+			},
+			"domains": {
+				MarkdownDescription: "The domains you want to verify.",
+				Type:                types.SetType{ElemType: types.StringType},
+				Required:            true,
+			},
+			"concurrency": {
+				MarkdownDescription: fmt.Sprintf("The maximum number of tokens requested at the same time. Defaults to `%d`.", defaultConcurrency),
+				Type:                types.Int64Type,
+				Optional:            true,
+			},
+			"records": {
+				MarkdownDescription: "The records you should create, keyed by domain as configured in `domains`.",
+				Computed:            true,
+				Attributes: tfsdk.MapNestedAttributes(map[string]tfsdk.Attribute{
+					"record_type": {
+						MarkdownDescription: "The type of DNS record you should create.",
+						Type:                types.StringType,
+						Computed:            true,
+					},
+					"record_name": {
+						MarkdownDescription: "The name of the record you should create, in normalized ASCII form.",
+						Type:                types.StringType,
+						Computed:            true,
+					},
+					"record_value": {
+						MarkdownDescription: "The value of the record you should create.",
+						Type:                types.StringType,
+						Computed:            true,
+					},
+				}),
+			},
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Read: true,
+			}),
+		},
+	}, nil
+}
+
+func (d *DomainsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(apiClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected apiClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+		return
+	}
+	d.client = data
+}
+
+func (d *DomainsDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var domains types.Set
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("domains"), &domains)...)
+	if resp.Diagnostics.HasError() || domains.IsNull() || domains.IsUnknown() {
+		return
+	}
+	for _, elem := range domains.Elems {
+		domain, ok := elem.(types.String)
+		if !ok || domain.IsNull() || domain.IsUnknown() {
+			continue
+		}
+		if _, err := domainname.Normalize(domain.Value); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("domains").AtSetValue(domain),
+				"Invalid Domain",
+				fmt.Sprintf("Unable to use %q as a domain name: %s", domain.Value, err),
+			)
+		}
+	}
+
+	var concurrency types.Int64
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("concurrency"), &concurrency)...)
+	if !concurrency.IsNull() && !concurrency.IsUnknown() && concurrency.Value < 1 {
+		resp.Diagnostics.AddAttributeError(path.Root("concurrency"),
+			"Invalid Concurrency",
+			fmt.Sprintf("The concurrency must be at least 1, got: %d.", concurrency.Value),
+		)
+	}
+}
+
+func (d *DomainsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data DomainsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	concurrency := defaultConcurrency
+	if !data.Concurrency.IsNull() {
+		concurrency = int(data.Concurrency.Value)
+	}
+
+	readTimeout := timeouts.Read(ctx, data.Timeouts, 60*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, concurrency)
+		errs   = map[string]error{}
+		result = make(map[string]DomainsDataSourceRecord, len(data.Domains))
+	)
+	for _, domain := range data.Domains {
+		wg.Add(1)
+		go func(domain string) {
+			defer wg.Done()
+			var (
+				record DomainsDataSourceRecord
+				err    error
+			)
+			select {
+			case sem <- struct{}{}:
+				// Both cases may be ready once the read times out.
+				if err = ctx.Err(); err == nil {
+					record, err = d.readRecord(ctx, domain)
+				}
+				<-sem
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[domain] = err
+				return
+			}
+			result[domain] = record
+		}(domain)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("domains"),
+			domainErrorsSummary(errs, "Client Error"),
+			domainErrors("read DNS token", errs, len(data.Domains)),
+		)
+		return
+	}
+
+	// Write logs using the tflog package
+	// Documentation: https://terraform.io/plugin/log
+	tflog.Trace(ctx, "read a data source", map[string]interface{}{
+		"domains": len(result),
+	})
+
+	data.ID = types.String{Value: domainsID(data.Domains)}
+	data.Records = result
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (d *DomainsDataSource) readRecord(ctx context.Context, domain string) (DomainsDataSourceRecord, error) {
+	normalized, err := domainname.Normalize(domain)
+	if err != nil {
+		return DomainsDataSourceRecord{}, err
+	}
+	token, err := d.client.GetToken(ctx, normalized)
+	if err != nil {
+		return DomainsDataSourceRecord{}, err
+	}
+	return DomainsDataSourceRecord{
+		RecordType:  types.String{Value: "TXT"},
+		RecordName:  types.String{Value: normalized},
+		RecordValue: types.String{Value: token},
+	}, nil
+}
+
+// domainsID returns a stable identifier for a set of domains.
+func domainsID(domains []string) string {
+	sorted := append([]string(nil), domains...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,34 @@
+package provider_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDomainsDataSource(t *testing.T) {
+	factories := testAccCassetteProviderFactories(t, "")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: factories,
+		Steps: []resource.TestStep{
+			// Read testing
+			{
+				Config: testAccDomainsDataSourceConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.googlesiteverification_domains.test", "records.%", "2"),
+					resource.TestCheckResourceAttr("data.googlesiteverification_domains.test", "records.example.com.record_type", "TXT"),
+					resource.TestCheckResourceAttr("data.googlesiteverification_domains.test", "records.Example.org..record_name", "example.org"),
+				),
+			},
+		},
+	})
+}
+
+const testAccDomainsDataSourceConfig = `
+data "googlesiteverification_domains" "test" {
+  domains     = ["example.com", "Example.org."]
+  concurrency = 2
+}
+`
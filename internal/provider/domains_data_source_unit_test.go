@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"giautm.dev/googlesiteverification/internal/fake"
+	"giautm.dev/googlesiteverification/verifier"
+)
+
+// testDomainsDataSource returns a data source configured against pd.
+func testDomainsDataSource(t *testing.T, pd *providerClient) (*DomainsDataSource, tfsdk.Schema) {
+	t.Helper()
+
+	d := &DomainsDataSource{}
+	resp := &datasource.ConfigureResponse{}
+	d.Configure(context.Background(), datasource.ConfigureRequest{ProviderData: pd}, resp)
+	wantDiagnostic(t, resp.Diagnostics, "")
+	schema, diags := d.GetSchema(context.Background())
+	wantDiagnostic(t, diags, "")
+	return d, schema
+}
+
+// testDomainsRead reads domains with the given concurrency and read timeout.
+func testDomainsRead(t *testing.T, d *DomainsDataSource, schema tfsdk.Schema, domains []string, concurrency int, read string) *datasource.ReadResponse {
+	t.Helper()
+
+	ctx := context.Background()
+	typ := schema.Type().TerraformType(ctx).(tftypes.Object)
+	elems := make([]tftypes.Value, len(domains))
+	for i, domain := range domains {
+		elems[i] = tftypes.NewValue(tftypes.String, domain)
+	}
+	config := tfsdk.Config{Schema: schema, Raw: testObject(t, schema, map[string]tftypes.Value{
+		"domains":     tftypes.NewValue(typ.AttributeTypes["domains"], elems),
+		"concurrency": tftypes.NewValue(tftypes.Number, concurrency),
+		"timeouts":    testTimeouts(schema, map[string]string{"read": read}),
+	})}
+	resp := &datasource.ReadResponse{State: tfsdk.State{Schema: schema, Raw: testObject(t, schema, nil)}}
+	d.Read(ctx, datasource.ReadRequest{Config: config}, resp)
+	return resp
+}
+
+func TestDomainsDataSourceRead(t *testing.T) {
+	ctx := context.Background()
+	s, pd := testFake(t)
+	d, schema := testDomainsDataSource(t, pd)
+
+	resp := testDomainsRead(t, d, schema, []string{"a.example.com", "B.Example.com."}, 2, "1s")
+	wantDiagnostic(t, resp.Diagnostics, "")
+	var data DomainsDataSourceModel
+	resp.Diagnostics.Append(resp.State.Get(ctx, &data)...)
+	wantDiagnostic(t, resp.Diagnostics, "")
+	if got := data.Records["B.Example.com."]; got.RecordName.Value != "b.example.com" || got.RecordValue.Value != s.Token("b.example.com") {
+		t.Errorf("records[B.Example.com.] = %+v", got)
+	}
+	if got := data.Records["a.example.com"]; got.RecordValue.Value != s.Token("a.example.com") {
+		t.Errorf("records[a.example.com] = %+v", got)
+	}
+}
+
+func TestDomainsDataSourceReadErrors(t *testing.T) {
+	s, pd := testFake(t)
+	s.Inject(fake.MethodGetToken, http.StatusForbidden, "Forbidden.", 1)
+	d, schema := testDomainsDataSource(t, pd)
+
+	// The invalid domain and the injected failure are one diagnostic.
+	resp := testDomainsRead(t, d, schema, []string{"a.example.com", "b.example.com", "co.uk"}, 1, "1s")
+	if got := resp.Diagnostics.ErrorsCount(); got != 1 {
+		t.Fatalf("diagnostics = %v, want a single error", resp.Diagnostics)
+	}
+	wantDiagnostic(t, resp.Diagnostics, "Unable to read DNS token for 2 of 3 domains")
+	wantDiagnostic(t, resp.Diagnostics, "- co.uk: ")
+	wantDiagnostic(t, resp.Diagnostics, "Forbidden.")
+}
+
+func TestDomainsDataSourceReadLimits(t *testing.T) {
+	domains := []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com", "f.example.com"}
+
+	t.Run("concurrency", func(t *testing.T) {
+		_, pd := testFake(t)
+		api := &countingAPI{API: pd.API}
+		pd.API = api
+		d, schema := testDomainsDataSource(t, pd)
+
+		resp := testDomainsRead(t, d, schema, domains, 2, "1s")
+		wantDiagnostic(t, resp.Diagnostics, "")
+		if api.max > 2 {
+			t.Errorf("%d token requests in flight, want at most 2", api.max)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, pd := testFake(t)
+		api := &blockingAPI{API: pd.API}
+		pd.API = api
+		d, schema := testDomainsDataSource(t, pd)
+
+		// The first request outlasts the read, the others are never sent.
+		resp := testDomainsRead(t, d, schema, domains, 1, "50ms")
+		wantDiagnostic(t, resp.Diagnostics, "context deadline exceeded")
+		if api.calls != 1 {
+			t.Errorf("%d token requests, want 1", api.calls)
+		}
+	})
+}
+
+// blockingAPI counts GetToken calls, which block until ctx is done.
+type blockingAPI struct {
+	verifier.API
+	calls int
+}
+
+func (a *blockingAPI) GetToken(ctx context.Context, domain string) (string, error) {
+	a.calls++
+	<-ctx.Done()
+	return "", ctx.Err()
+}
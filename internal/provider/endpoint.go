@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+const endpointEnvVar = "GOOGLE_SITEVERIFICATION_ENDPOINT"
+
+// endpoint returns the base URL of the Site Verification API from the
+// endpoint attribute or GOOGLE_SITEVERIFICATION_ENDPOINT, with the trailing
+// slash the generated client expects. It returns an empty string for the
+// production endpoint.
+func (m *GoogleSiteVerificationProviderModel) endpoint(getenv func(string) string) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	endpoint, source := m.Endpoint.Value, "the endpoint attribute"
+	if endpoint == "" {
+		endpoint, source = getenv(endpointEnvVar), "the "+endpointEnvVar+" environment variable"
+	}
+	if endpoint == "" {
+		return "", diags
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		diags.AddAttributeError(path.Root("endpoint"),
+			"Invalid endpoint",
+			fmt.Sprintf("The endpoint %q from %s must be an absolute http or https URL, for example \"http://localhost:8080/siteVerification/v1/\".", endpoint, source),
+		)
+		return "", diags
+	}
+	if !strings.HasSuffix(endpoint, "/") {
+		endpoint += "/"
+	}
+	return endpoint, diags
+}
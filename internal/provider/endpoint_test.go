@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestEndpoint(t *testing.T) {
+	for name, tt := range map[string]struct {
+		attribute string
+		env       string
+		want      string
+		wantError bool
+	}{
+		"default":        {},
+		"attribute":      {attribute: "http://localhost:8080/", env: "http://env/", want: "http://localhost:8080/"},
+		"env":            {env: "https://fake.example.com/siteVerification/v1/", want: "https://fake.example.com/siteVerification/v1/"},
+		"trailing slash": {attribute: "http://localhost:8080", want: "http://localhost:8080/"},
+		"relative":       {attribute: "localhost:8080", wantError: true},
+		"scheme":         {env: "ftp://localhost/", wantError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := GoogleSiteVerificationProviderModel{Endpoint: types.String{Value: tt.attribute}}
+			got, diags := m.endpoint(func(name string) string {
+				if name == endpointEnvVar {
+					return tt.env
+				}
+				return ""
+			})
+			if diags.HasError() != tt.wantError {
+				t.Fatalf("endpoint() diagnostics = %v, want error %t", diags, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("endpoint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigureEmulator(t *testing.T) {
+	t.Setenv(endpointEnvVar, "")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Authorization = %q, want none", got)
+		}
+		if r.URL.Path != "/webResource/dns://example.com" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"id":"dns%3A%2F%2Fexample.com"}`))
+	}))
+	defer srv.Close()
+
+	for name, tt := range map[string]struct {
+		values    map[string]tftypes.Value
+		env       string
+		wantError bool
+	}{
+		"attribute": {
+			values: map[string]tftypes.Value{
+				"emulator": tftypes.NewValue(tftypes.Bool, true),
+				"endpoint": tftypes.NewValue(tftypes.String, srv.URL),
+				// Credentials are ignored in the emulator mode.
+				"credentials": tftypes.NewValue(tftypes.String, "/does/not/exist.json"),
+			},
+		},
+		"env": {
+			values: map[string]tftypes.Value{
+				"emulator": tftypes.NewValue(tftypes.Bool, true),
+			},
+			env: srv.URL,
+		},
+		"missing endpoint": {
+			values: map[string]tftypes.Value{
+				"emulator": tftypes.NewValue(tftypes.Bool, true),
+			},
+			wantError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(endpointEnvVar, tt.env)
+			resp := &provider.ConfigureResponse{}
+			(&GoogleSiteVerificationProvider{}).Configure(context.Background(), provider.ConfigureRequest{
+				Config: testProviderConfig(t, tt.values),
+			}, resp)
+			if resp.Diagnostics.HasError() != tt.wantError {
+				t.Fatalf("Configure() diagnostics = %v, want error %t", resp.Diagnostics, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			data, ok := resp.ResourceData.(*providerClient)
+			if !ok {
+				t.Fatalf("ResourceData = %T, want *providerClient", resp.ResourceData)
+			}
+			if _, err := data.Get(context.Background(), "dns://example.com"); err != nil {
+				t.Fatalf("Get() returned error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package provider
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+)
+
+// NewWithTransport is like New, but sends the API requests of the provider
+// through wrap and retries every retryInterval.
+func NewWithTransport(version string, wrap func(http.RoundTripper) http.RoundTripper, retryInterval time.Duration) func() provider.Provider {
+	return func() provider.Provider {
+		return &GoogleSiteVerificationProvider{
+			version:       version,
+			transport:     wrap,
+			retryInterval: retryInterval,
+		}
+	}
+}
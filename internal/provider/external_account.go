@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+const externalAccountType = "external_account"
+
+// externalAccountSubjectTokenTypes are the subject token types accepted by
+// the Security Token Service.
+var externalAccountSubjectTokenTypes = map[string]bool{
+	"urn:ietf:params:oauth:token-type:jwt":        true,
+	"urn:ietf:params:oauth:token-type:id_token":   true,
+	"urn:ietf:params:oauth:token-type:saml2":      true,
+	"urn:ietf:params:aws:token-type:aws4_request": true,
+}
+
+// externalAccountConfig is the part of a Workload Identity Federation
+// credential configuration that the provider validates before use.
+type externalAccountConfig struct {
+	Type                           string `json:"type"`
+	Audience                       string `json:"audience"`
+	SubjectTokenType               string `json:"subject_token_type"`
+	TokenURL                       string `json:"token_url"`
+	ServiceAccountImpersonationURL string `json:"service_account_impersonation_url"`
+	CredentialSource               *struct {
+		File       string            `json:"file"`
+		URL        string            `json:"url"`
+		Headers    map[string]string `json:"headers"`
+		Executable *struct {
+			Command string `json:"command"`
+		} `json:"executable"`
+		EnvironmentID string `json:"environment_id"`
+		Format        struct {
+			Type                  string `json:"type"`
+			SubjectTokenFieldName string `json:"subject_token_field_name"`
+		} `json:"format"`
+	} `json:"credential_source"`
+}
+
+// validate returns the problems found in the configuration, each naming the
+// offending field. Subject token files that do not exist yet are reported as
+// warnings, since they are read again on every token refresh.
+func (c *externalAccountConfig) validate() (problems, warnings []string) {
+	if c.Audience == "" {
+		problems = append(problems, `"audience" is required, for example "//iam.googleapis.com/projects/PROJECT_NUMBER/locations/global/workloadIdentityPools/POOL_ID/providers/PROVIDER_ID".`)
+	}
+	if c.SubjectTokenType == "" {
+		problems = append(problems, `"subject_token_type" is required.`)
+	} else if !externalAccountSubjectTokenTypes[c.SubjectTokenType] {
+		problems = append(problems, fmt.Sprintf(`"subject_token_type" %q is not supported, expected one of the urn:ietf:params:oauth:token-type:* types.`, c.SubjectTokenType))
+	}
+	if c.TokenURL == "" {
+		problems = append(problems, `"token_url" is required, usually "https://sts.googleapis.com/v1/token".`)
+	} else if !isHTTPSURL(c.TokenURL) {
+		problems = append(problems, fmt.Sprintf(`"token_url" %q must be an https URL.`, c.TokenURL))
+	}
+	if u := c.ServiceAccountImpersonationURL; u != "" && !isHTTPSURL(u) {
+		problems = append(problems, fmt.Sprintf(`"service_account_impersonation_url" %q must be an https URL.`, u))
+	}
+
+	src := c.CredentialSource
+	if src == nil {
+		problems = append(problems, `"credential_source" is required.`)
+		return problems, warnings
+	}
+	var sources int
+	for _, set := range []bool{src.File != "", src.URL != "" && src.EnvironmentID == "", src.Executable != nil, src.EnvironmentID != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		problems = append(problems, `"credential_source" must set exactly one of "file", "url", "executable" or "environment_id".`)
+	}
+	if src.File != "" {
+		if _, err := os.Stat(src.File); err != nil {
+			warnings = append(warnings, fmt.Sprintf(`"credential_source.file" %q could not be read yet: %s.`, src.File, err))
+		}
+	}
+	if src.URL != "" {
+		if u, err := url.Parse(src.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf(`"credential_source.url" %q must be an http or https URL.`, src.URL))
+		}
+	}
+	if src.Executable != nil && src.Executable.Command == "" {
+		problems = append(problems, `"credential_source.executable.command" is required.`)
+	}
+	switch src.Format.Type {
+	case "", "text":
+	case "json":
+		if src.Format.SubjectTokenFieldName == "" {
+			problems = append(problems, `"credential_source.format.subject_token_field_name" is required when "credential_source.format.type" is "json".`)
+		}
+	default:
+		problems = append(problems, fmt.Sprintf(`"credential_source.format.type" %q must be "text" or "json".`, src.Format.Type))
+	}
+	return problems, warnings
+}
+
+func isHTTPSURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
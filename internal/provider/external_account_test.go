@@ -0,0 +1,223 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+	"google.golang.org/api/siteverification/v1"
+)
+
+// rewriteTransport sends every request to a local test server.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// testSTS is a local Security Token Service and Site Verification API. It
+// exchanges wantSubjectToken for an access token, and serves the subject
+// token itself at /subject for URL-sourced credentials.
+func testSTS(t *testing.T, wantSubjectToken string) (*httptest.Server, context.Context) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/subject":
+			if r.Header.Get("Metadata-Flavor") != "Test" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"value": wantSubjectToken})
+		case r.URL.Path == "/v1/token":
+			if err := r.ParseForm(); err != nil {
+				t.Error(err)
+			}
+			if got := r.Form.Get("grant_type"); got != "urn:ietf:params:oauth:grant-type:token-exchange" {
+				t.Errorf("grant_type = %q", got)
+			}
+			if got := r.Form.Get("subject_token"); got != wantSubjectToken {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprintf(w, `{"error":"invalid_grant","error_description":"unexpected subject token %q"}`, got)
+				return
+			}
+			if got := r.Form.Get("scope"); got != siteverification.SiteverificationScope {
+				t.Errorf("scope = %q", got)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"federated-token","issued_token_type":"urn:ietf:params:oauth:token-type:access_token","token_type":"Bearer","expires_in":3600}`))
+		case strings.HasPrefix(r.URL.Path, "/webResource/"):
+			if got := r.Header.Get("Authorization"); got != "Bearer federated-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error":{"code":401,"message":"Request had invalid authentication credentials."}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"dns%3A%2F%2Fexample.com"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	target, _ := url.Parse(srv.URL)
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{
+		Transport: rewriteTransport{target: target},
+	})
+	return srv, ctx
+}
+
+func testExternalAccount(source map[string]interface{}) string {
+	b, _ := json.Marshal(map[string]interface{}{
+		"type":               "external_account",
+		"audience":           "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/pool/providers/github",
+		"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+		"token_url":          "https://sts.googleapis.com/v1/token",
+		"credential_source":  source,
+	})
+	return string(b)
+}
+
+func TestExternalAccountCredentials(t *testing.T) {
+	const subjectToken = "subject-jwt"
+	srv, tokenCtx := testSTS(t, subjectToken)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(subjectToken), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range map[string]string{
+		"file": testExternalAccount(map[string]interface{}{
+			"file": tokenFile,
+		}),
+		"url": testExternalAccount(map[string]interface{}{
+			"url":     srv.URL + "/subject",
+			"headers": map[string]string{"Metadata-Flavor": "Test"},
+			"format": map[string]string{
+				"type":                     "json",
+				"subject_token_field_name": "value",
+			},
+		}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := credentialsSource{name: "credentials", value: value, attribute: true}
+			opt, diags := src.option(context.Background(), tokenCtx, []string{siteverification.SiteverificationScope}, "")
+			if diags.HasError() {
+				t.Fatalf("option() returned errors: %v", diags)
+			}
+			svc, err := siteverification.NewService(context.Background(), opt, option.WithEndpoint(srv.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := svc.WebResource.Get("dns://example.com").Do(); err != nil {
+				t.Fatalf("Get() returned error: %s", err)
+			}
+		})
+	}
+}
+
+func TestExternalAccountCredentialsFromFile(t *testing.T) {
+	const subjectToken = "subject-jwt"
+	srv, tokenCtx := testSTS(t, subjectToken)
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte(subjectToken), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "credentials.json")
+	if err := os.WriteFile(configFile, []byte(testExternalAccount(map[string]interface{}{"file": tokenFile})), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	src := credentialsSource{name: "GOOGLE_APPLICATION_CREDENTIALS", value: configFile}
+	opt, diags := src.option(context.Background(), tokenCtx, []string{siteverification.SiteverificationScope}, "")
+	if diags.HasError() {
+		t.Fatalf("option() returned errors: %v", diags)
+	}
+	svc, err := siteverification.NewService(context.Background(), opt, option.WithEndpoint(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.WebResource.Get("dns://example.com").Do(); err != nil {
+		t.Fatalf("Get() returned error: %s", err)
+	}
+}
+
+func TestExternalAccountValidation(t *testing.T) {
+	for name, tt := range map[string]struct {
+		config      string
+		wantProblem string
+	}{
+		"missing audience": {
+			config:      `{"type":"external_account","subject_token_type":"urn:ietf:params:oauth:token-type:jwt","token_url":"https://sts.googleapis.com/v1/token","credential_source":{"file":"/dev/null"}}`,
+			wantProblem: `"audience" is required`,
+		},
+		"bad subject token type": {
+			config:      `{"type":"external_account","audience":"a","subject_token_type":"jwt","token_url":"https://sts.googleapis.com/v1/token","credential_source":{"file":"/dev/null"}}`,
+			wantProblem: `"subject_token_type" "jwt" is not supported`,
+		},
+		"http token url": {
+			config:      `{"type":"external_account","audience":"a","subject_token_type":"urn:ietf:params:oauth:token-type:jwt","token_url":"http://sts.googleapis.com/v1/token","credential_source":{"file":"/dev/null"}}`,
+			wantProblem: `"token_url" "http://sts.googleapis.com/v1/token" must be an https URL`,
+		},
+		"missing credential source": {
+			config:      `{"type":"external_account","audience":"a","subject_token_type":"urn:ietf:params:oauth:token-type:jwt","token_url":"https://sts.googleapis.com/v1/token"}`,
+			wantProblem: `"credential_source" is required`,
+		},
+		"file and url": {
+			config:      `{"type":"external_account","audience":"a","subject_token_type":"urn:ietf:params:oauth:token-type:jwt","token_url":"https://sts.googleapis.com/v1/token","credential_source":{"file":"/dev/null","url":"http://localhost/token"}}`,
+			wantProblem: `must set exactly one of "file", "url", "executable" or "environment_id"`,
+		},
+		"json format without field": {
+			config:      `{"type":"external_account","audience":"a","subject_token_type":"urn:ietf:params:oauth:token-type:jwt","token_url":"https://sts.googleapis.com/v1/token","credential_source":{"url":"http://localhost/token","format":{"type":"json"}}}`,
+			wantProblem: `"credential_source.format.subject_token_field_name" is required`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := credentialsSource{name: "credentials", value: tt.config, attribute: true}
+			_, diags := src.option(context.Background(), context.Background(), []string{siteverification.SiteverificationScope}, "")
+			if !diags.HasError() {
+				t.Fatal("option() succeeded, want error")
+			}
+			var found bool
+			for _, d := range diags.Errors() {
+				if strings.Contains(d.Detail(), tt.wantProblem) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("option() errors = %v, want one containing %q", diags, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestExternalAccountMissingTokenFileWarns(t *testing.T) {
+	value := testExternalAccount(map[string]interface{}{
+		"file": filepath.Join(t.TempDir(), "missing"),
+	})
+	src := credentialsSource{name: "credentials", value: value, attribute: true}
+	_, diags := src.option(context.Background(), context.Background(), []string{siteverification.SiteverificationScope}, "")
+	if diags.HasError() {
+		t.Fatalf("option() returned errors: %v", diags)
+	}
+	if diags.WarningsCount() != 1 {
+		t.Errorf("option() warnings = %v, want one", diags)
+	}
+}
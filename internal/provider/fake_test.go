@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"google.golang.org/api/option"
+	"google.golang.org/api/siteverification/v1"
+
+	"giautm.dev/googlesiteverification/internal/apierror"
+	"giautm.dev/googlesiteverification/internal/fake"
+	"giautm.dev/googlesiteverification/verifier"
+)
+
+// testRetryInterval replaces the one minute between verification attempts.
+const testRetryInterval = time.Millisecond
+
+// testFake starts an in-process fake of the Site Verification API and returns
+// the provider data of a provider configured against it.
+func testFake(t *testing.T, opts ...fake.Option) (*fake.Server, *providerClient) {
+	t.Helper()
+
+	s := fake.New(opts...)
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+
+	svc, err := siteverification.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithEndpoint(srv.URL+"/siteVerification/v1/"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, &providerClient{
+		API:           verifier.NewAPI(svc),
+		caps:          capabilities{read: true, unverify: true},
+		retryInterval: testRetryInterval,
+	}
+}
+
+// testObject returns a value of the schema with the given attributes set and
+// every other attribute and block null.
+func testObject(t *testing.T, schema tfsdk.Schema, values map[string]tftypes.Value) tftypes.Value {
+	t.Helper()
+
+	typ := schema.Type().TerraformType(context.Background()).(tftypes.Object)
+	attrs := map[string]tftypes.Value{}
+	for name, attrType := range typ.AttributeTypes {
+		attrs[name] = tftypes.NewValue(attrType, nil)
+	}
+	for name, value := range values {
+		if _, ok := typ.AttributeTypes[name]; !ok {
+			t.Fatalf("unknown attribute %q", name)
+		}
+		attrs[name] = value
+	}
+	return tftypes.NewValue(typ, attrs)
+}
+
+// testTimeouts returns a timeouts block of the schema with the given
+// timeouts set, for example "create": "50ms".
+func testTimeouts(schema tfsdk.Schema, values map[string]string) tftypes.Value {
+	typ := schema.Type().TerraformType(context.Background()).(tftypes.Object).AttributeTypes["timeouts"].(tftypes.Object)
+	attrs := map[string]tftypes.Value{}
+	for name := range typ.AttributeTypes {
+		attrs[name] = tftypes.NewValue(tftypes.String, nil)
+	}
+	for name, value := range values {
+		attrs[name] = tftypes.NewValue(tftypes.String, value)
+	}
+	return tftypes.NewValue(typ, attrs)
+}
+
+func TestFakeErrors(t *testing.T) {
+	// The retry loops must recognize the errors of the fake.
+	for _, tt := range []struct {
+		method string
+		want   apierror.Kind
+	}{
+		{method: fake.MethodInsert, want: apierror.TokenNotFound},
+		{method: fake.MethodDelete, want: apierror.TokenStillPresent},
+	} {
+		s, data := testFake(t, fake.WithPublishedCheck(fake.Never()), fake.WithRemovedCheck(fake.Never()))
+		s.Put("example.com")
+		var err error
+		if tt.method == fake.MethodInsert {
+			_, err = data.Insert(context.Background(), "example.org")
+		} else {
+			err = data.Delete(context.Background(), "dns://example.com")
+		}
+		if got := apierror.Classify(err); got != tt.want {
+			t.Errorf("Classify(%v) = %s on %s, want %s", err, got, tt.method, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"giautm.dev/googlesiteverification/internal/domainname"
+)
+
+// normalizeDomainModifier plans a computed attribute as the normalized form
+// of the domain configured at path.
+type normalizeDomainModifier struct {
+	path path.Path
+}
+
+var _ tfsdk.AttributePlanModifier = normalizeDomainModifier{}
+
+func normalizeDomain(p path.Path) tfsdk.AttributePlanModifier {
+	return normalizeDomainModifier{path: p}
+}
+
+func (m normalizeDomainModifier) Description(ctx context.Context) string {
+	return "Plans the value as the lowercased, punycode form of the domain, without a trailing dot."
+}
+
+func (m normalizeDomainModifier) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m normalizeDomainModifier) Modify(ctx context.Context, req tfsdk.ModifyAttributePlanRequest, resp *tfsdk.ModifyAttributePlanResponse) {
+	// Nothing to plan when the resource is being destroyed.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var domain types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, m.path, &domain)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if domain.IsUnknown() || domain.IsNull() {
+		resp.AttributePlan = types.String{Unknown: true}
+		return
+	}
+
+	normalized, err := domainname.Normalize(domain.Value)
+	if err != nil {
+		// domainValidator reports the error against the configured attribute.
+		return
+	}
+	resp.AttributePlan = types.String{Value: normalized}
+}
+
+// requiresReplaceIfDomainChanged requires replacement only when the normalized
+// domain changes, so that "Example.com." and "example.com" share a plan.
+func requiresReplaceIfDomainChanged() tfsdk.AttributePlanModifier {
+	return resource.RequiresReplaceIf(
+		func(ctx context.Context, state, config attr.Value, path path.Path) (bool, diag.Diagnostics) {
+			var before, after types.String
+			diags := tfsdk.ValueAs(ctx, state, &before)
+			diags.Append(tfsdk.ValueAs(ctx, config, &after)...)
+			if diags.HasError() {
+				return false, diags
+			}
+			if before.IsNull() || after.IsUnknown() {
+				return !before.Equal(after), diags
+			}
+			return !domainname.Equal(before.Value, after.Value), diags
+		},
+		"Requires replacement if the normalized domain changes.",
+		"Requires replacement if the normalized domain changes.",
+	)
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+)
+
+// protocol6Resource stands in for a resource with nested attributes when
+// serving over protocol version 5. Its schema declares the nested attributes
+// as plain object typed attributes, so that configurations still parse, and
+// every operation fails with an error explaining that protocol version 6 is
+// required.
+type protocol6Resource struct {
+	resource resource.Resource
+	typeName string
+}
+
+var (
+	_ resource.Resource                   = &protocol6Resource{}
+	_ resource.ResourceWithValidateConfig = &protocol6Resource{}
+)
+
+func newProtocol6Resource(f func() resource.Resource) func() resource.Resource {
+	return func() resource.Resource {
+		r := f()
+		var resp resource.MetadataResponse
+		r.Metadata(context.Background(), resource.MetadataRequest{ProviderTypeName: "googlesiteverification"}, &resp)
+		return &protocol6Resource{resource: r, typeName: resp.TypeName}
+	}
+}
+
+func (r *protocol6Resource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	r.resource.Metadata(ctx, req, resp)
+}
+
+func (r *protocol6Resource) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	schema, diags := r.resource.GetSchema(ctx)
+	return flattenSchema(schema), diags
+}
+
+func (r *protocol6Resource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	resp.Diagnostics.Append(protocol6Required(r.typeName))
+}
+
+func (r *protocol6Resource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(protocol6Required(r.typeName))
+}
+
+func (r *protocol6Resource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	resp.Diagnostics.Append(protocol6Required(r.typeName))
+}
+
+func (r *protocol6Resource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(protocol6Required(r.typeName))
+}
+
+func (r *protocol6Resource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	resp.Diagnostics.Append(protocol6Required(r.typeName))
+}
+
+// protocol6DataSource is the data source counterpart of protocol6Resource.
+type protocol6DataSource struct {
+	dataSource datasource.DataSource
+	typeName   string
+}
+
+var (
+	_ datasource.DataSource                   = &protocol6DataSource{}
+	_ datasource.DataSourceWithValidateConfig = &protocol6DataSource{}
+)
+
+func newProtocol6DataSource(f func() datasource.DataSource) func() datasource.DataSource {
+	return func() datasource.DataSource {
+		d := f()
+		var resp datasource.MetadataResponse
+		d.Metadata(context.Background(), datasource.MetadataRequest{ProviderTypeName: "googlesiteverification"}, &resp)
+		return &protocol6DataSource{dataSource: d, typeName: resp.TypeName}
+	}
+}
+
+func (d *protocol6DataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	d.dataSource.Metadata(ctx, req, resp)
+}
+
+func (d *protocol6DataSource) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	schema, diags := d.dataSource.GetSchema(ctx)
+	return flattenSchema(schema), diags
+}
+
+func (d *protocol6DataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	resp.Diagnostics.Append(protocol6Required(d.typeName))
+}
+
+func (d *protocol6DataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	resp.Diagnostics.Append(protocol6Required(d.typeName))
+}
+
+func protocol6Required(typeName string) diag.Diagnostic {
+	return diag.NewErrorDiagnostic("Unsupported Protocol Version",
+		fmt.Sprintf("%s uses nested attributes, which require Terraform plugin protocol version 6 (Terraform 1.0 or later). "+
+			"The provider is served over protocol version 5: upgrade Terraform, or serve it with -protocol=6.", typeName))
+}
+
+// flattenSchema returns schema with its nested attributes declared as object
+// typed attributes, which protocol version 5 supports. Validators and plan
+// modifiers are dropped, since the configuration is rejected anyway.
+func flattenSchema(schema tfsdk.Schema) tfsdk.Schema {
+	schema.Attributes = flattenAttributes(schema.Attributes)
+	schema.Blocks = flattenBlocks(schema.Blocks)
+	return schema
+}
+
+func flattenAttributes(attrs map[string]tfsdk.Attribute) map[string]tfsdk.Attribute {
+	flat := make(map[string]tfsdk.Attribute, len(attrs))
+	for name, attr := range attrs {
+		attr.Type = attr.FrameworkType()
+		attr.Attributes = nil
+		attr.Validators = nil
+		attr.PlanModifiers = nil
+		flat[name] = attr
+	}
+	return flat
+}
+
+func flattenBlocks(blocks map[string]tfsdk.Block) map[string]tfsdk.Block {
+	if blocks == nil {
+		return nil
+	}
+	flat := make(map[string]tfsdk.Block, len(blocks))
+	for name, block := range blocks {
+		block.Attributes = flattenAttributes(block.Attributes)
+		block.Blocks = flattenBlocks(block.Blocks)
+		block.Validators = nil
+		block.PlanModifiers = nil
+		flat[name] = block
+	}
+	return flat
+}
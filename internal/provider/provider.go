@@ -2,9 +2,10 @@ package provider
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
@@ -13,8 +14,12 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"google.golang.org/api/option"
 	"google.golang.org/api/siteverification/v1"
+	htransport "google.golang.org/api/transport/http"
+
+	"giautm.dev/googlesiteverification/verifier"
 )
 
 type (
@@ -24,17 +29,38 @@ type (
 		// provider is built and ran locally, and "test" when running acceptance
 		// testing.
 		version string
+		// transport wraps the HTTP transport of the API client, and
+		// retryInterval overrides defaultRetryInterval. Both are only set by
+		// tests recording or replaying API interactions.
+		transport     func(http.RoundTripper) http.RoundTripper
+		retryInterval time.Duration
+		// protocol5 replaces the resources and data sources that cannot be
+		// served over protocol version 5 with ones that fail with a clear
+		// error.
+		protocol5 bool
 	}
 	// GoogleSiteVerificationProviderModel describes the provider data model.
 	GoogleSiteVerificationProviderModel struct {
-		Credentials types.String `tfsdk:"credentials"`
+		Credentials                        types.String `tfsdk:"credentials"`
+		AccessToken                        types.String `tfsdk:"access_token"`
+		ImpersonateServiceAccount          types.String `tfsdk:"impersonate_service_account"`
+		ImpersonateServiceAccountDelegates types.List   `tfsdk:"impersonate_service_account_delegates"`
+		IAMCredentialsEndpoint             types.String `tfsdk:"iam_credentials_endpoint"`
+		Subject                            types.String `tfsdk:"subject"`
+		Scopes                             types.List   `tfsdk:"scopes"`
+		UserProjectOverride                types.Bool   `tfsdk:"user_project_override"`
+		BillingProject                     types.String `tfsdk:"billing_project"`
+		RequestReason                      types.String `tfsdk:"request_reason"`
+		Endpoint                           types.String `tfsdk:"endpoint"`
+		Emulator                           types.Bool   `tfsdk:"emulator"`
 	}
 )
 
 // Ensure GoogleSiteVerificationProvider satisfies various provider interfaces.
 var (
-	_ provider.Provider             = &GoogleSiteVerificationProvider{}
-	_ provider.ProviderWithMetadata = &GoogleSiteVerificationProvider{}
+	_ provider.Provider                     = &GoogleSiteVerificationProvider{}
+	_ provider.ProviderWithMetadata         = &GoogleSiteVerificationProvider{}
+	_ provider.ProviderWithConfigValidators = &GoogleSiteVerificationProvider{}
 )
 
 func New(version string) func() provider.Provider {
@@ -45,6 +71,19 @@ func New(version string) func() provider.Provider {
 	}
 }
 
+// NewProtocol5 is like New, for serving over protocol version 5. Resources
+// and data sources with nested attributes, which the protocol does not
+// support, are kept so that configurations parse, but fail with an error
+// asking for protocol version 6.
+func NewProtocol5(version string) func() provider.Provider {
+	return func() provider.Provider {
+		return &GoogleSiteVerificationProvider{
+			version:   version,
+			protocol5: true,
+		}
+	}
+}
+
 func (p *GoogleSiteVerificationProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
 	resp.TypeName = "googlesiteverification"
 	resp.Version = p.version
@@ -52,9 +91,71 @@ func (p *GoogleSiteVerificationProvider) Metadata(ctx context.Context, req provi
 
 func (p *GoogleSiteVerificationProvider) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
+		MarkdownDescription: "Manages domain ownership verifications with the [Google Site Verification API](https://developers.google.com/site-verification).\n\n" +
+			"The provider is served over plugin protocol version 6 by default, and over version 5 for Terraform 0.12 to 0.14, which only support that version. " +
+			"Protocol version 5 does not support nested attributes, so `googlesiteverification_domain_set` and the `googlesiteverification_domains` data source fail with an error asking for protocol version 6 there: use Terraform 1.0 or later to manage them.",
 		Attributes: map[string]tfsdk.Attribute{
 			"credentials": {
-				MarkdownDescription: "Either the path to or the contents of a [service account key file](https://cloud.google.com/iam/docs/creating-managing-service-account-keys) in JSON format. If not provided, the [application default credentials](https://cloud.google.com/sdk/gcloud/reference/auth/application-default) will be used.",
+				MarkdownDescription: "Either the path to or the contents of a [service account key file](https://cloud.google.com/iam/docs/creating-managing-service-account-keys) or a [Workload Identity Federation](https://cloud.google.com/iam/docs/workload-identity-federation) `external_account` configuration in JSON format. Federation configurations may read the subject token from a file or a URL, and are validated before use. If not provided, the `GOOGLE_CREDENTIALS`, `GOOGLE_CLOUD_KEYFILE_JSON`, `GCLOUD_KEYFILE_JSON` and `GOOGLE_APPLICATION_CREDENTIALS` environment variables are checked in that order, after `GOOGLE_OAUTH_ACCESS_TOKEN`. If none is set, the [application default credentials](https://cloud.google.com/sdk/gcloud/reference/auth/application-default) will be used.",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"access_token": {
+				MarkdownDescription: "A temporary [OAuth 2.0 access token](https://developers.google.com/identity/protocols/oauth2) with the `https://www.googleapis.com/auth/siteverification` scope. It cannot be refreshed, so requests fail with a clear error once it expires. Conflicts with `credentials`. May also be set with the `GOOGLE_OAUTH_ACCESS_TOKEN` environment variable, which is used when neither attribute is configured.",
+				Optional:            true,
+				Sensitive:           true,
+				Type:                types.StringType,
+			},
+			"impersonate_service_account": {
+				MarkdownDescription: "The email of a service account to impersonate. Short-lived access tokens are minted through the [IAM Credentials API](https://cloud.google.com/iam/docs/create-short-lived-credentials-direct) using `credentials` or the application default credentials, which need `roles/iam.serviceAccountTokenCreator` on it. Verifications are owned by the impersonated service account.",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"impersonate_service_account_delegates": {
+				MarkdownDescription: "The emails of the service accounts in a [delegation chain](https://cloud.google.com/iam/docs/create-short-lived-credentials-delegated) leading to `impersonate_service_account`.",
+				Optional:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"iam_credentials_endpoint": {
+				MarkdownDescription: "The base URL of the IAM Credentials API used by `impersonate_service_account`. Defaults to `https://iamcredentials.googleapis.com/`.",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"scopes": {
+				MarkdownDescription: "The OAuth 2.0 scopes requested for the provider credentials, or held by `access_token`. Defaults to `https://www.googleapis.com/auth/siteverification`, which permits every operation. `https://www.googleapis.com/auth/siteverification.verify_only` alone only permits reading tokens and verifying domains: data sources and verification work as usual, but `googlesiteverification_domain` and `googlesiteverification_domain_set` trust their state on refresh and import with a warning, and removing them leaves the verification in place.",
+				Optional:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+				Validators: []tfsdk.AttributeValidator{
+					scopesValidator{},
+				},
+			},
+			"user_project_override": {
+				MarkdownDescription: "Whether to bill API quota to `billing_project` instead of the project that owns the credentials. The credentials need `serviceusage.services.use` on that project.",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"billing_project": {
+				MarkdownDescription: "The project billed for API quota when `user_project_override` is `true`, sent as the `X-Goog-User-Project` header.",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"request_reason": {
+				MarkdownDescription: "A justification sent with every request as the `X-Goog-Request-Reason` header, recorded in [Cloud Audit Logs](https://cloud.google.com/logging/docs/audit).",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"endpoint": {
+				MarkdownDescription: "The base URL of the Site Verification API, for example a local fake used in tests. Defaults to `https://www.googleapis.com/siteVerification/v1/`. May also be set with the `GOOGLE_SITEVERIFICATION_ENDPOINT` environment variable.",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"emulator": {
+				MarkdownDescription: "Whether `endpoint` is an emulator that does not need authentication. Requests are sent without credentials, and the credential attributes and environment variables are ignored. Requires `endpoint` or `GOOGLE_SITEVERIFICATION_ENDPOINT`.",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"subject": {
+				MarkdownDescription: "The email of a Google Workspace user to act as through [domain-wide delegation](https://developers.google.com/identity/protocols/oauth2/service-account#delegatingauthority), so verifications are owned by that user instead of the service account. Requires a `service_account` key from `credentials` or one of its environment variables, whose client ID is granted the `https://www.googleapis.com/auth/siteverification` scope in the Workspace admin console. Conflicts with `access_token` and `impersonate_service_account`.",
 				Optional:            true,
 				Type:                types.StringType,
 			},
@@ -62,6 +163,14 @@ func (p *GoogleSiteVerificationProvider) GetSchema(ctx context.Context) (tfsdk.S
 	}, nil
 }
 
+func (p *GoogleSiteVerificationProvider) ConfigValidators(ctx context.Context) []provider.ConfigValidator {
+	return []provider.ConfigValidator{
+		conflicting(path.Root("credentials"), path.Root("access_token")),
+		conflicting(path.Root("subject"), path.Root("access_token")),
+		conflicting(path.Root("subject"), path.Root("impersonate_service_account")),
+	}
+}
+
 func (p *GoogleSiteVerificationProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
 	var data GoogleSiteVerificationProviderModel
 
@@ -70,42 +179,162 @@ func (p *GoogleSiteVerificationProvider) Configure(ctx context.Context, req prov
 		return
 	}
 
+	srv, scopes, diags := p.newService(ctx, data)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	caps := scopeCapabilities(scopes)
+	if !caps.read {
+		tflog.Info(ctx, "Restricted scopes, verifications are not read or unverified", map[string]interface{}{
+			"scopes": scopes,
+		})
+	}
+	retryInterval := defaultRetryInterval
+	if p.retryInterval > 0 {
+		retryInterval = p.retryInterval
+	}
+	pc := &providerClient{API: verifier.NewAPI(srv), caps: caps, retryInterval: retryInterval}
+	resp.DataSourceData = pc
+	resp.ResourceData = pc
+}
+
+// newService returns the Site Verification API client configured by data,
+// along with the OAuth 2.0 scopes it is authorized for.
+func (p *GoogleSiteVerificationProvider) newService(ctx context.Context, data GoogleSiteVerificationProviderModel) (*siteverification.Service, []string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	if len(data.ImpersonateServiceAccountDelegates.Elems) > 0 && data.ImpersonateServiceAccount.Value == "" {
+		diags.AddAttributeError(path.Root("impersonate_service_account_delegates"),
+			"Missing impersonate_service_account",
+			"Delegates can only be used together with impersonate_service_account.",
+		)
+		return nil, nil, diags
+	}
+
+	if data.UserProjectOverride.Value && data.BillingProject.Value == "" && !data.BillingProject.IsUnknown() {
+		diags.AddAttributeError(path.Root("billing_project"),
+			"Missing billing_project",
+			"A billing project is required when user_project_override is true.",
+		)
+		return nil, nil, diags
+	}
+	if !data.UserProjectOverride.Value && data.BillingProject.Value != "" {
+		diags.AddAttributeWarning(path.Root("billing_project"),
+			"Unused billing_project",
+			"The billing project is only used when user_project_override is true, API quota is billed to the project that owns the credentials.",
+		)
+	}
+
+	scopes, d := data.scopes(ctx)
+	diags.Append(d...)
+	if diags.HasError() {
+		return nil, nil, diags
+	}
+	endpoint, d := data.endpoint(os.Getenv)
+	diags.Append(d...)
+	if diags.HasError() {
+		return nil, nil, diags
+	}
+
 	var opts []option.ClientOption
-	if customCreds := data.Credentials.Value; customCreds != "" {
-		var optCreds option.ClientOption
-		if json.Valid([]byte(customCreds)) {
-			optCreds = option.WithCredentialsJSON([]byte(customCreds))
-		} else {
-			if _, err := os.Stat(customCreds); err != nil {
-				resp.Diagnostics.AddAttributeError(path.Root("credentials"),
-					"Invalid credentials",
-					fmt.Sprintf("The credentials file %q could not be found.", customCreds),
-				)
-				return
-			}
-			optCreds = option.WithCredentialsFile(customCreds)
+	if data.Emulator.Value {
+		if endpoint == "" {
+			diags.AddAttributeError(path.Root("emulator"),
+				"Missing endpoint",
+				fmt.Sprintf("The emulator mode requires the endpoint attribute or the %s environment variable, so that unauthenticated requests are not sent to Google.", endpointEnvVar),
+			)
+			return nil, nil, diags
+		}
+		tflog.Debug(ctx, "Using emulator without authentication", map[string]interface{}{
+			"endpoint": endpoint,
+		})
+		opts = append(opts, option.WithoutAuthentication())
+		opts = append(opts, data.requestOptions().clientOptions()...)
+	} else {
+		opts, d = data.clientOptions(ctx, scopes)
+		diags.Append(d...)
+		if diags.HasError() {
+			return nil, nil, diags
+		}
+	}
+	if p.transport != nil {
+		client, _, err := htransport.NewClient(context.Background(), opts...)
+		if err != nil {
+			diags.AddError(
+				"Unable to create siteverification service",
+				fmt.Sprintf("Unable to create HTTP client: %s", err),
+			)
+			return nil, nil, diags
+		}
+		base := client.Transport
+		if base == nil {
+			base = http.DefaultTransport
 		}
-		opts = append(opts, optCreds)
+		opts = []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: p.transport(base)})}
+	}
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
 	}
 	srv, err := siteverification.NewService(context.Background(), opts...)
 	if err != nil {
-		resp.Diagnostics.AddError(
+		diags.AddError(
 			"Unable to create siteverification service",
 			fmt.Sprintf("Unable to create siteverification service: %s", err),
 		)
+		return nil, nil, diags
 	}
-	resp.DataSourceData = srv
-	resp.ResourceData = srv
+	return srv, scopes, diags
 }
 
 func (p *GoogleSiteVerificationProvider) Resources(ctx context.Context) []func() resource.Resource {
-	return []func() resource.Resource{
+	resources := []func() resource.Resource{
 		NewDomainResource,
+		NewDomainSetResource,
+	}
+	if !p.protocol5 {
+		return resources
+	}
+	served := make([]func() resource.Resource, 0, len(resources))
+	for _, f := range resources {
+		if schema, _ := f().GetSchema(ctx); hasNestedAttributes(schema.Attributes, schema.Blocks) {
+			f = newProtocol6Resource(f)
+		}
+		served = append(served, f)
 	}
+	return served
 }
 
 func (p *GoogleSiteVerificationProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
-	return []func() datasource.DataSource{
+	dataSources := []func() datasource.DataSource{
 		NewDomainDataSource,
+		NewDomainsDataSource,
+	}
+	if !p.protocol5 {
+		return dataSources
+	}
+	served := make([]func() datasource.DataSource, 0, len(dataSources))
+	for _, f := range dataSources {
+		if schema, _ := f().GetSchema(ctx); hasNestedAttributes(schema.Attributes, schema.Blocks) {
+			f = newProtocol6DataSource(f)
+		}
+		served = append(served, f)
+	}
+	return served
+}
+
+// hasNestedAttributes reports whether a schema uses nested attributes, which
+// protocol version 5 cannot serve. Blocks are supported by both protocols.
+func hasNestedAttributes(attrs map[string]tfsdk.Attribute, blocks map[string]tfsdk.Block) bool {
+	for _, attr := range attrs {
+		if attr.Attributes != nil {
+			return true
+		}
+	}
+	for _, block := range blocks {
+		if hasNestedAttributes(block.Attributes, block.Blocks) {
+			return true
+		}
 	}
+	return false
 }
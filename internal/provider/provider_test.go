@@ -1,8 +1,14 @@
 package provider_test
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"giautm.dev/googlesiteverification/internal/acctest"
+	"giautm.dev/googlesiteverification/internal/cassette"
 	"giautm.dev/googlesiteverification/internal/provider"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
@@ -16,8 +22,92 @@ var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServe
 	"googlesiteverification": providerserver.NewProtocol6WithError(provider.New("test")()),
 }
 
+const (
+	// testAccTestDomainSuffix is appended to a random label to name the test
+	// domains, which are inside testAccTestZone.
+	testAccTestDomainSuffix = "-test-terraform-provider.giautm.xyz"
+	testAccTestZone         = "giautm.xyz"
+	// testAccReplayLabel replaces the random label of the test domain in
+	// recorded cassettes, and is used instead of it on replay.
+	testAccReplayLabel = "00000000-0000-0000-0000-000000000000"
+)
+
 func testAccPreCheck(t *testing.T) {
 	// You can add code here to run prior to any test case execution, for example assertions
 	// about the appropriate environment variables being set are common to see in a pre-check
 	// function.
 }
+
+// testAccReplay reports whether acceptance tests replay the API interactions
+// recorded in testdata/cassettes instead of calling Google and Cloudflare.
+func testAccReplay() bool {
+	return os.Getenv("TF_ACC_REPLAY") == "1"
+}
+
+// testAccRecord reports whether acceptance tests record their API
+// interactions to testdata/cassettes.
+func testAccRecord() bool {
+	return os.Getenv("TF_ACC_RECORD") == "1"
+}
+
+// testAccCassetteProviderFactories returns the provider factories of a test
+// whose test domain is named label+testAccTestDomainSuffix, or that uses no
+// random domain when label is empty. With
+// TF_ACC_RECORD=1 the interactions with the Site Verification API are saved,
+// scrubbed, to testdata/cassettes/<test name>.json. With TF_ACC_REPLAY=1 they
+// are answered from that file instead, and Cloudflare is served by a local
+// stub, so that the test needs neither credentials nor secrets.
+func testAccCassetteProviderFactories(t *testing.T, label string) map[string]func() (tfprotov6.ProviderServer, error) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "cassettes", t.Name()+".json")
+	var (
+		c             *cassette.Cassette
+		err           error
+		retryInterval time.Duration
+	)
+	switch {
+	case testAccReplay():
+		c, err = cassette.New(path, cassette.ModeReplay)
+		if errors.Is(err, os.ErrNotExist) {
+			t.Skipf("No cassette at %s, record one with TF_ACC_RECORD=1.", path)
+		}
+		retryInterval = time.Millisecond
+		t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "replayed-access-token")
+		t.Setenv("GOOGLE_SITEVERIFICATION_ENDPOINT", "")
+		acctest.NewCloudflare(t, testAccTestZone).Setenv(t)
+	case testAccRecord():
+		var replacements []string
+		if label != "" {
+			replacements = []string{label, testAccReplayLabel}
+		}
+		c, err = cassette.New(path, cassette.ModeRecord, replacements...)
+		t.Cleanup(func() {
+			if t.Failed() {
+				return
+			}
+			if err := c.Save(); err != nil {
+				t.Errorf("Unable to save cassette: %s", err)
+			}
+		})
+	default:
+		return testAccProtoV6ProviderFactories
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]func() (tfprotov6.ProviderServer, error){
+		"googlesiteverification": providerserver.NewProtocol6WithError(
+			provider.NewWithTransport("test", c.Transport, retryInterval)()),
+	}
+}
+
+// testAccDomainLabel returns a random label for a test domain, or
+// testAccReplayLabel when replaying.
+func testAccDomainLabel(random string) string {
+	if testAccReplay() {
+		return testAccReplayLabel
+	}
+	return random
+}
@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"google.golang.org/api/siteverification/v1"
+)
+
+// knownScopes are the OAuth 2.0 scopes accepted by the scopes attribute.
+var knownScopes = []string{
+	siteverification.SiteverificationScope,
+	siteverification.SiteverificationVerifyOnlyScope,
+}
+
+// capabilities describes the API methods permitted by the configured scopes.
+// The verify_only scope only grants getToken and insert.
+type capabilities struct {
+	// read reports whether verifications can be read with get and list.
+	read bool
+	// unverify reports whether verifications can be deleted.
+	unverify bool
+}
+
+func scopeCapabilities(scopes []string) capabilities {
+	for _, scope := range scopes {
+		if scope == siteverification.SiteverificationScope {
+			return capabilities{read: true, unverify: true}
+		}
+	}
+	return capabilities{}
+}
+
+// scopes returns the configured scopes, or the full siteverification scope.
+func (m *GoogleSiteVerificationProviderModel) scopes(ctx context.Context) ([]string, diag.Diagnostics) {
+	if m.Scopes.IsNull() || m.Scopes.IsUnknown() {
+		return []string{siteverification.SiteverificationScope}, nil
+	}
+	var scopes []string
+	diags := m.Scopes.ElementsAs(ctx, &scopes, false)
+	return scopes, diags
+}
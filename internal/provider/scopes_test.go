@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/siteverification/v1"
+)
+
+func TestScopeCapabilities(t *testing.T) {
+	for name, tt := range map[string]struct {
+		scopes []string
+		want   capabilities
+	}{
+		"full":        {scopes: []string{siteverification.SiteverificationScope}, want: capabilities{read: true, unverify: true}},
+		"both":        {scopes: []string{siteverification.SiteverificationVerifyOnlyScope, siteverification.SiteverificationScope}, want: capabilities{read: true, unverify: true}},
+		"verify only": {scopes: []string{siteverification.SiteverificationVerifyOnlyScope}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := scopeCapabilities(tt.scopes); got != tt.want {
+				t.Errorf("scopeCapabilities() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testDomainResourceState returns the state of a verified example.com.
+func testDomainResourceState(t *testing.T) tfsdk.State {
+	t.Helper()
+
+	ctx := context.Background()
+	schema, diags := (&DomainResource{}).GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatalf("GetSchema() returned errors: %v", diags)
+	}
+	typ := schema.Type().TerraformType(ctx).(tftypes.Object)
+	return tfsdk.State{
+		Schema: schema,
+		Raw: tftypes.NewValue(typ, map[string]tftypes.Value{
+			"id":                tftypes.NewValue(tftypes.String, "dns://example.com"),
+			"domain":            tftypes.NewValue(tftypes.String, "example.com"),
+			"normalized_domain": tftypes.NewValue(tftypes.String, "example.com"),
+			"token":             tftypes.NewValue(tftypes.String, "google-site-verification=token"),
+			"timeouts":          tftypes.NewValue(typ.AttributeTypes["timeouts"], nil),
+		}),
+	}
+}
+
+// verifyOnlyClient permits the methods of the verify_only scope and fails
+// the test on every other call.
+type verifyOnlyClient struct {
+	t *testing.T
+}
+
+func (c verifyOnlyClient) Capabilities() capabilities {
+	return scopeCapabilities([]string{siteverification.SiteverificationVerifyOnlyScope})
+}
+
+func (c verifyOnlyClient) RetryInterval() time.Duration {
+	return testRetryInterval
+}
+
+func (c verifyOnlyClient) GetToken(ctx context.Context, domain string) (string, error) {
+	return "google-site-verification=token", nil
+}
+
+func (c verifyOnlyClient) Insert(ctx context.Context, domain string) (*siteverification.SiteVerificationWebResourceResource, error) {
+	return &siteverification.SiteVerificationWebResourceResource{Id: "dns%3A%2F%2F" + domain}, nil
+}
+
+func (c verifyOnlyClient) Get(ctx context.Context, id string) (*siteverification.SiteVerificationWebResourceResource, error) {
+	return nil, c.forbidden("get")
+}
+
+func (c verifyOnlyClient) List(ctx context.Context) ([]*siteverification.SiteVerificationWebResourceResource, error) {
+	return nil, c.forbidden("list")
+}
+
+func (c verifyOnlyClient) Update(ctx context.Context, id string, res *siteverification.SiteVerificationWebResourceResource) (*siteverification.SiteVerificationWebResourceResource, error) {
+	return nil, c.forbidden("update")
+}
+
+func (c verifyOnlyClient) Delete(ctx context.Context, id string) error {
+	return c.forbidden("delete")
+}
+
+func (c verifyOnlyClient) forbidden(method string) error {
+	c.t.Errorf("unexpected %s call", method)
+	return &googleapi.Error{Code: http.StatusForbidden, Message: "Request had insufficient authentication scopes."}
+}
+
+func TestDomainResourceVerifyOnly(t *testing.T) {
+	ctx := context.Background()
+	r, _ := testDomainResource(t, verifyOnlyClient{t: t})
+	state := testDomainResourceState(t)
+
+	wantWarning := func(t *testing.T, diags diag.Diagnostics) {
+		t.Helper()
+		if diags.HasError() || diags.WarningsCount() != 1 {
+			t.Errorf("diagnostics = %v, want a single warning", diags)
+		}
+	}
+
+	t.Run("read", func(t *testing.T) {
+		resp := &resource.ReadResponse{State: tfsdk.State{Schema: state.Schema}}
+		r.Read(ctx, resource.ReadRequest{State: state}, resp)
+		wantWarning(t, resp.Diagnostics)
+		if !resp.State.Raw.Equal(state.Raw) {
+			t.Errorf("Read() state = %v, want prior state", resp.State.Raw)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		resp := &resource.DeleteResponse{State: state}
+		r.Delete(ctx, resource.DeleteRequest{State: state}, resp)
+		wantWarning(t, resp.Diagnostics)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		resp := &resource.ImportStateResponse{State: tfsdk.State{
+			Schema: state.Schema,
+			Raw:    tftypes.NewValue(state.Schema.Type().TerraformType(ctx), nil),
+		}}
+		r.ImportState(ctx, resource.ImportStateRequest{ID: "Example.com"}, resp)
+		wantWarning(t, resp.Diagnostics)
+		var data DomainResourceModel
+		if diags := resp.State.Get(ctx, &data); diags.HasError() {
+			t.Fatal(diags)
+		}
+		if data.Id.Value != "dns://example.com" || data.Token.Value != "google-site-verification=token" {
+			t.Errorf("ImportState() state = %+v", data)
+		}
+	})
+}
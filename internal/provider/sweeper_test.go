@@ -0,0 +1,219 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+
+	"giautm.dev/googlesiteverification/internal/fake"
+	"giautm.dev/googlesiteverification/internal/siteid"
+	"giautm.dev/googlesiteverification/verifier"
+)
+
+const (
+	// sweepPatternEnvVar overrides defaultSweepPattern, the glob matched
+	// against the domains left behind by acceptance tests.
+	sweepPatternEnvVar  = "GOOGLE_SITEVERIFICATION_SWEEP_PATTERN"
+	defaultSweepPattern = "*-test-terraform-provider.giautm.xyz"
+	// sweepTimeout bounds the unverify of a single domain.
+	sweepTimeout = 30 * time.Second
+)
+
+func TestMain(m *testing.M) {
+	resource.TestMain(m)
+}
+
+func init() {
+	resource.AddTestSweepers("googlesiteverification_domain", &resource.Sweeper{
+		Name: "googlesiteverification_domain",
+		F: func(region string) error {
+			ctx := context.Background()
+			data, err := sweeperProviderData(ctx)
+			if err != nil {
+				return err
+			}
+			pattern := os.Getenv(sweepPatternEnvVar)
+			if pattern == "" {
+				pattern = defaultSweepPattern
+			}
+			return sweepDomains(ctx, data, pattern)
+		},
+	})
+}
+
+// sweeperProviderData configures the provider from the environment, as an
+// empty provider block would.
+func sweeperProviderData(ctx context.Context) (*providerClient, error) {
+	p := &GoogleSiteVerificationProvider{version: "test"}
+	schema, diags := p.GetSchema(ctx)
+	if diags.HasError() {
+		return nil, fmt.Errorf("unable to get provider schema: %v", diags)
+	}
+	typ := schema.Type().TerraformType(ctx).(tftypes.Object)
+	attrs := map[string]tftypes.Value{}
+	for name, attrType := range typ.AttributeTypes {
+		attrs[name] = tftypes.NewValue(attrType, nil)
+	}
+
+	resp := &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: tfsdk.Config{Schema: schema, Raw: tftypes.NewValue(typ, attrs)},
+	}, resp)
+	if resp.Diagnostics.HasError() {
+		return nil, fmt.Errorf("unable to configure provider: %v", resp.Diagnostics)
+	}
+	return resp.ResourceData.(*providerClient), nil
+}
+
+// sweepDomains unverifies every domain verification of the identity whose
+// domain matches the glob pattern, defaultConcurrency at a time. The TXT
+// records of the domains must be deleted beforehand: a domain whose token is
+// still published is skipped after one attempt instead of waiting for it.
+func sweepDomains(ctx context.Context, data *providerClient, pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid sweep pattern %q: %w", pattern, err)
+	}
+	items, err := data.List(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list verifications: %w", err)
+	}
+
+	var (
+		ids    []string
+		failed []string
+	)
+	for _, item := range items {
+		if item.Site == nil || item.Site.Type != verifier.TypeDomain {
+			continue
+		}
+		domain := strings.ToLower(item.Site.Identifier)
+		if ok, _ := path.Match(pattern, domain); !ok {
+			continue
+		}
+		site, err := siteid.Parse(item.Id)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", domain, err))
+			continue
+		}
+		ids = append(ids, site.ID())
+	}
+
+	op := &setOperation{api: &limitedAPI{API: data, sem: make(chan struct{}, defaultConcurrency)}}
+	errs := op.forEach(ctx, ids, func(ctx context.Context, id string) error {
+		log.Printf("[INFO] Sweeping verification %s", id)
+		ctx, cancel := context.WithTimeout(ctx, sweepTimeout)
+		defer cancel()
+		var present error
+		err := verifier.New(op.api, verifier.WithRetryHook(func(ctx context.Context, domain string, err error) {
+			present = err
+			cancel()
+		})).Unverify(ctx, id)
+		if present != nil {
+			return fmt.Errorf("skipped, delete its TXT record first: %w", present)
+		}
+		return err
+	})
+	for _, id := range sortedKeys(errs) {
+		failed = append(failed, fmt.Sprintf("%s: %s", id, errs[id]))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to sweep %d verifications: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func TestSweepDomains(t *testing.T) {
+	s, data := testFake(t)
+	leftover := "0f8fad5b-d9cb-469f-a165-70867728950e-test-terraform-provider.giautm.xyz"
+	s.Put(leftover)
+	s.Put("Other-Test-Terraform-Provider.giautm.xyz")
+	s.Put("giautm.xyz")
+	s.Put("example.com")
+
+	if err := sweepDomains(context.Background(), data, defaultSweepPattern); err != nil {
+		t.Fatalf("sweepDomains() returned error: %s", err)
+	}
+	for domain, want := range map[string]bool{
+		leftover: false,
+		"other-test-terraform-provider.giautm.xyz": false,
+		"giautm.xyz":  true,
+		"example.com": true,
+	} {
+		if got := s.Verified(domain); got != want {
+			t.Errorf("Verified(%s) = %t after sweeping, want %t", domain, got, want)
+		}
+	}
+	if got := s.Calls(fake.MethodDelete); got != 2 {
+		t.Errorf("Calls(delete) = %d, want 2", got)
+	}
+}
+
+func TestSweepDomainsErrors(t *testing.T) {
+	t.Run("list", func(t *testing.T) {
+		s, data := testFake(t)
+		s.Inject(fake.MethodList, http.StatusForbidden, "The caller does not have permission", 1)
+		if err := sweepDomains(context.Background(), data, defaultSweepPattern); err == nil {
+			t.Error("sweepDomains() returned no error when listing fails")
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		s, data := testFake(t)
+		s.Put("a-test-terraform-provider.giautm.xyz")
+		s.Put("b-test-terraform-provider.giautm.xyz")
+		s.Inject(fake.MethodDelete, http.StatusForbidden, "You are not an owner of this site.", 1)
+		err := sweepDomains(context.Background(), data, defaultSweepPattern)
+		if err == nil || !strings.Contains(err.Error(), "unable to sweep 1 verifications") {
+			t.Errorf("sweepDomains() returned error %v, want one failed verification", err)
+		}
+	})
+	t.Run("token still published", func(t *testing.T) {
+		s, data := testFake(t, fake.WithRemovedCheck(fake.Never()))
+		s.Put("a-test-terraform-provider.giautm.xyz")
+		s.Put("b-test-terraform-provider.giautm.xyz")
+		err := sweepDomains(context.Background(), data, defaultSweepPattern)
+		if err == nil || !strings.Contains(err.Error(), "unable to sweep 2 verifications") || !strings.Contains(err.Error(), "delete its TXT record first") {
+			t.Errorf("sweepDomains() returned error %v, want both domains skipped", err)
+		}
+		if got := s.Calls(fake.MethodDelete); got != 2 {
+			t.Errorf("Calls(delete) = %d, want a single attempt per domain", got)
+		}
+	})
+	t.Run("pattern", func(t *testing.T) {
+		_, data := testFake(t)
+		if err := sweepDomains(context.Background(), data, "["); err == nil {
+			t.Error("sweepDomains() returned no error for an invalid pattern")
+		}
+	})
+}
+
+func TestSweeperProviderData(t *testing.T) {
+	s := fake.New()
+	s.Put("a-test-terraform-provider.giautm.xyz")
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	t.Setenv(accessTokenEnvVar, "sweeper-token")
+	t.Setenv(endpointEnvVar, srv.URL)
+
+	data, err := sweeperProviderData(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sweepDomains(context.Background(), data, defaultSweepPattern); err != nil {
+		t.Fatalf("sweepDomains() returned error: %s", err)
+	}
+	if s.Verified("a-test-terraform-provider.giautm.xyz") {
+		t.Error("Verified() = true after sweeping")
+	}
+}
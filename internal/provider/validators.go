@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"giautm.dev/googlesiteverification/internal/domainname"
+)
+
+// domainValidator rejects values that cannot be normalized into a verifiable
+// domain name, including bare public suffixes.
+type domainValidator struct{}
+
+var _ tfsdk.AttributeValidator = domainValidator{}
+
+func (v domainValidator) Description(ctx context.Context) string {
+	return "value must be a valid domain name that is not a public suffix"
+}
+
+func (v domainValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v domainValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var value types.String
+	resp.Diagnostics.Append(tfsdk.ValueAs(ctx, req.AttributeConfig, &value)...)
+	if resp.Diagnostics.HasError() || value.IsNull() || value.IsUnknown() {
+		return
+	}
+
+	if _, err := domainname.Normalize(value.Value); err != nil {
+		if errors.Is(err, domainname.ErrPublicSuffix) {
+			suffix, _ := domainname.Canonical(value.Value)
+			resp.Diagnostics.AddAttributeError(req.AttributePath,
+				"Invalid Domain",
+				fmt.Sprintf("%q is a public suffix and cannot be verified. Use a domain registered under it instead, for example \"example.%s\".", value.Value, suffix),
+			)
+			return
+		}
+		resp.Diagnostics.AddAttributeError(req.AttributePath,
+			"Invalid Domain",
+			fmt.Sprintf("Unable to use %q as a domain name: %s", value.Value, err),
+		)
+	}
+}
+
+// scopesValidator rejects OAuth 2.0 scopes the Site Verification API does not
+// know about.
+type scopesValidator struct{}
+
+var _ tfsdk.AttributeValidator = scopesValidator{}
+
+func (v scopesValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("each value must be one of %s", strings.Join(knownScopes, ", "))
+}
+
+func (v scopesValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v scopesValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var value types.List
+	resp.Diagnostics.Append(tfsdk.ValueAs(ctx, req.AttributeConfig, &value)...)
+	if resp.Diagnostics.HasError() || value.IsNull() || value.IsUnknown() {
+		return
+	}
+
+	if len(value.Elems) == 0 {
+		resp.Diagnostics.AddAttributeError(req.AttributePath,
+			"Invalid Scopes",
+			"At least one scope is required. Remove the attribute to use the default scope.",
+		)
+		return
+	}
+	for i, elem := range value.Elems {
+		scope, ok := elem.(types.String)
+		if !ok || scope.IsNull() || scope.IsUnknown() {
+			continue
+		}
+		var known bool
+		for _, s := range knownScopes {
+			known = known || scope.Value == s
+		}
+		if !known {
+			resp.Diagnostics.AddAttributeError(req.AttributePath.AtListIndex(i),
+				"Invalid Scope",
+				fmt.Sprintf("Unsupported scope %q, %s.", scope.Value, v.Description(ctx)),
+			)
+		}
+	}
+}
+
+// conflictingValidator rejects provider configurations that set more than
+// one of the attributes at paths.
+type conflictingValidator struct {
+	paths []path.Path
+}
+
+var _ provider.ConfigValidator = conflictingValidator{}
+
+func conflicting(paths ...path.Path) provider.ConfigValidator {
+	return conflictingValidator{paths: paths}
+}
+
+func (v conflictingValidator) Description(ctx context.Context) string {
+	names := make([]string, len(v.paths))
+	for i, p := range v.paths {
+		names[i] = p.String()
+	}
+	return fmt.Sprintf("only one of %s can be configured", strings.Join(names, ", "))
+}
+
+func (v conflictingValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v conflictingValidator) ValidateProvider(ctx context.Context, req provider.ValidateConfigRequest, resp *provider.ValidateConfigResponse) {
+	var configured []path.Path
+	for _, p := range v.paths {
+		var value attr.Value
+		resp.Diagnostics.Append(req.Config.GetAttribute(ctx, p, &value)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		if !value.IsNull() {
+			configured = append(configured, p)
+		}
+	}
+	if len(configured) > 1 {
+		for _, p := range configured {
+			resp.Diagnostics.AddAttributeError(p,
+				"Conflicting Attributes",
+				fmt.Sprintf("Invalid provider configuration: %s.", v.Description(ctx)),
+			)
+		}
+	}
+}
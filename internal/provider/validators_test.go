@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// testProviderConfig returns a provider configuration with the given
+// attributes set and every other attribute null.
+func testProviderConfig(t *testing.T, values map[string]tftypes.Value) tfsdk.Config {
+	t.Helper()
+
+	ctx := context.Background()
+	schema, diags := (&GoogleSiteVerificationProvider{}).GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatalf("GetSchema() returned errors: %v", diags)
+	}
+	typ := schema.Type().TerraformType(ctx).(tftypes.Object)
+	attrs := map[string]tftypes.Value{}
+	for name, attrType := range typ.AttributeTypes {
+		attrs[name] = tftypes.NewValue(attrType, nil)
+	}
+	for name, value := range values {
+		attrs[name] = value
+	}
+	return tfsdk.Config{
+		Schema: schema,
+		Raw:    tftypes.NewValue(typ, attrs),
+	}
+}
+
+func TestConflictingValidator(t *testing.T) {
+	v := conflicting(path.Root("credentials"), path.Root("access_token"))
+	for name, tt := range map[string]struct {
+		values    map[string]tftypes.Value
+		wantError bool
+	}{
+		"none": {},
+		"credentials": {
+			values: map[string]tftypes.Value{
+				"credentials": tftypes.NewValue(tftypes.String, "{}"),
+			},
+		},
+		"access_token": {
+			values: map[string]tftypes.Value{
+				"access_token": tftypes.NewValue(tftypes.String, "token"),
+			},
+		},
+		"both": {
+			values: map[string]tftypes.Value{
+				"credentials":  tftypes.NewValue(tftypes.String, "{}"),
+				"access_token": tftypes.NewValue(tftypes.String, "token"),
+			},
+			wantError: true,
+		},
+		"unknown": {
+			values: map[string]tftypes.Value{
+				"credentials":  tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+				"access_token": tftypes.NewValue(tftypes.String, "token"),
+			},
+			wantError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := &provider.ValidateConfigResponse{}
+			v.ValidateProvider(context.Background(), provider.ValidateConfigRequest{
+				Config: testProviderConfig(t, tt.values),
+			}, resp)
+			if got := resp.Diagnostics.HasError(); got != tt.wantError {
+				t.Errorf("HasError() = %t, want %t: %v", got, tt.wantError, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestScopesValidator(t *testing.T) {
+	for name, tt := range map[string]struct {
+		scopes    []string
+		wantError bool
+	}{
+		"full":        {scopes: []string{"https://www.googleapis.com/auth/siteverification"}},
+		"verify only": {scopes: []string{"https://www.googleapis.com/auth/siteverification.verify_only"}},
+		"empty":       {scopes: []string{}, wantError: true},
+		"unknown":     {scopes: []string{"https://www.googleapis.com/auth/cloud-platform"}, wantError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			elems := make([]tftypes.Value, len(tt.scopes))
+			for i, scope := range tt.scopes {
+				elems[i] = tftypes.NewValue(tftypes.String, scope)
+			}
+			config := testProviderConfig(t, map[string]tftypes.Value{
+				"scopes": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, elems),
+			})
+			var value attr.Value
+			if diags := config.GetAttribute(context.Background(), path.Root("scopes"), &value); diags.HasError() {
+				t.Fatal(diags)
+			}
+			resp := &tfsdk.ValidateAttributeResponse{}
+			scopesValidator{}.Validate(context.Background(), tfsdk.ValidateAttributeRequest{
+				AttributePath:   path.Root("scopes"),
+				AttributeConfig: value,
+				Config:          config,
+			}, resp)
+			if got := resp.Diagnostics.HasError(); got != tt.wantError {
+				t.Errorf("HasError() = %t, want %t: %v", got, tt.wantError, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestDomainValidator(t *testing.T) {
+	for name, tt := range map[string]struct {
+		domain     string
+		wantDetail string
+	}{
+		"valid":         {domain: "Example.com."},
+		"invalid":       {domain: "exa mple.com", wantDetail: "Unable to use"},
+		"public suffix": {domain: "CO.UK.", wantDetail: `for example "example.co.uk"`},
+		"idn suffix":    {domain: "公司.cn", wantDetail: `for example "example.xn--55qx5d.cn"`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := &tfsdk.ValidateAttributeResponse{}
+			domainValidator{}.Validate(context.Background(), tfsdk.ValidateAttributeRequest{
+				AttributePath:   path.Root("domain"),
+				AttributeConfig: types.String{Value: tt.domain},
+			}, resp)
+			if tt.wantDetail == "" {
+				if resp.Diagnostics.HasError() {
+					t.Errorf("Validate() returned errors: %v", resp.Diagnostics)
+				}
+				return
+			}
+			if len(resp.Diagnostics) != 1 || !strings.Contains(resp.Diagnostics[0].Detail(), tt.wantDetail) {
+				t.Errorf("Validate() = %v, want a detail containing %q", resp.Diagnostics, tt.wantDetail)
+			}
+		})
+	}
+}
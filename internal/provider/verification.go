@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/time/rate"
+
+	"giautm.dev/googlesiteverification/verifier"
+)
+
+const (
+	// defaultRetryInterval is how long to wait before retrying while a token
+	// is not yet visible in DNS, or still visible when unverifying.
+	defaultRetryInterval = verifier.DefaultRetryInterval
+	// defaultTimeout bounds every operation without a configured timeout.
+	defaultTimeout = 5 * defaultRetryInterval
+)
+
+// newVerifier returns a verifier of api that waits for limiter, if not nil,
+// and retries every interval. The records are managed in Terraform, so it
+// does not publish tokens.
+func newVerifier(api verifier.API, limiter *rate.Limiter, interval time.Duration) *verifier.Verifier {
+	return verifier.New(api,
+		verifier.WithRateLimiter(limiter),
+		verifier.WithRetryInterval(interval),
+		verifier.WithRetryHook(func(ctx context.Context, domain string, err error) {
+			msg := "Trying to create verification again"
+			if errors.Is(err, verifier.ErrTokenStillPresent) {
+				msg = "Trying to delete verification again"
+			}
+			tflog.Warn(ctx, msg, map[string]interface{}{
+				"domain": domain,
+			})
+		}),
+	)
+}
+
+func wait(ctx context.Context, limiter *rate.Limiter) error {
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
@@ -0,0 +1,117 @@
+// Package records renders DNS verification records in the formats used by
+// DNS tooling outside of Terraform.
+package records
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxTXTStringLen is the maximum length of a single character-string in a
+// TXT record, longer values are split into several strings.
+const maxTXTStringLen = 255
+
+// Record is a single DNS record.
+type Record struct {
+	// Name is the record name relative to its zone, "@" for the zone apex.
+	Name string `json:"name"`
+	// FQDN is the fully qualified record name, with a trailing dot.
+	FQDN  string `json:"fqdn"`
+	Type  string `json:"type"`
+	TTL   int64  `json:"ttl"`
+	Value string `json:"value"`
+}
+
+// BIND renders the record as a zone file line using its fully qualified name.
+func BIND(r Record) string {
+	return fmt.Sprintf("%s\t%d\tIN\t%s\t%s", r.FQDN, r.TTL, r.Type, rdata(r))
+}
+
+// Route53ChangeBatch renders the record as an AWS Route53 ChangeBatch that
+// upserts it, suitable for `aws route53 change-resource-record-sets`.
+func Route53ChangeBatch(r Record) (string, error) {
+	type resourceRecord struct {
+		Value string
+	}
+	type resourceRecordSet struct {
+		Name            string
+		Type            string
+		TTL             int64
+		ResourceRecords []resourceRecord
+	}
+	type change struct {
+		Action            string
+		ResourceRecordSet resourceRecordSet
+	}
+	batch := struct {
+		Changes []change
+	}{
+		Changes: []change{{
+			Action: "UPSERT",
+			ResourceRecordSet: resourceRecordSet{
+				Name:            r.FQDN,
+				Type:            r.Type,
+				TTL:             r.TTL,
+				ResourceRecords: []resourceRecord{{Value: rdata(r)}},
+			},
+		}},
+	}
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// OctoDNS renders the record as an octoDNS YAML zone entry.
+func OctoDNS(r Record) string {
+	name := r.Name
+	if name == "@" {
+		name = ""
+	}
+	value := r.Value
+	if r.Type == "TXT" {
+		// octoDNS requires semicolons in TXT values to be escaped.
+		value = strings.ReplaceAll(value, ";", `\;`)
+	}
+	return fmt.Sprintf("%s:\n  type: %s\n  ttl: %d\n  value: %s\n",
+		strconv.Quote(name), r.Type, r.TTL, strconv.Quote(value))
+}
+
+// DNSControl renders the record as a dnscontrol record modifier.
+func DNSControl(r Record) string {
+	return fmt.Sprintf("%s(%s, %s, TTL(%d))", r.Type, strconv.Quote(r.Name), strconv.Quote(r.Value), r.TTL)
+}
+
+// JSON renders the record as a generic JSON object.
+func JSON(r Record) (string, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// rdata returns the presentation format of the record data, quoting and
+// splitting TXT values as required.
+func rdata(r Record) string {
+	if r.Type != "TXT" {
+		return r.Value
+	}
+	value := r.Value
+	var parts []string
+	for len(value) > maxTXTStringLen {
+		parts = append(parts, quoteTXT(value[:maxTXTStringLen]))
+		value = value[maxTXTStringLen:]
+	}
+	parts = append(parts, quoteTXT(value))
+	return strings.Join(parts, " ")
+}
+
+func quoteTXT(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,80 @@
+package records_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"giautm.dev/googlesiteverification/internal/records"
+)
+
+var record = records.Record{
+	Name:  "app.eu",
+	FQDN:  "app.eu.example.com.",
+	Type:  "TXT",
+	TTL:   300,
+	Value: "google-site-verification=abc_DEF-123",
+}
+
+func TestBIND(t *testing.T) {
+	want := "app.eu.example.com.\t300\tIN\tTXT\t\"google-site-verification=abc_DEF-123\""
+	if got := records.BIND(record); got != want {
+		t.Errorf("BIND() = %q, want %q", got, want)
+	}
+}
+
+func TestBINDLongValue(t *testing.T) {
+	r := record
+	r.Value = strings.Repeat("a", 300) + `"\`
+	want := `"` + strings.Repeat("a", 255) + `" "` + strings.Repeat("a", 45) + `\"\\"`
+	if got := records.BIND(r); !strings.HasSuffix(got, "\t"+want) {
+		t.Errorf("BIND() = %q, want suffix %q", got, want)
+	}
+}
+
+func TestRoute53ChangeBatch(t *testing.T) {
+	got, err := records.Route53ChangeBatch(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Changes":[{"Action":"UPSERT","ResourceRecordSet":{"Name":"app.eu.example.com.","Type":"TXT","TTL":300,"ResourceRecords":[{"Value":"\"google-site-verification=abc_DEF-123\""}]}}]}`
+	if got != want {
+		t.Errorf("Route53ChangeBatch() = %s, want %s", got, want)
+	}
+}
+
+func TestOctoDNS(t *testing.T) {
+	want := "\"app.eu\":\n  type: TXT\n  ttl: 300\n  value: \"google-site-verification=abc_DEF-123\"\n"
+	if got := records.OctoDNS(record); got != want {
+		t.Errorf("OctoDNS() = %q, want %q", got, want)
+	}
+
+	apex := record
+	apex.Name = "@"
+	apex.Value = "a;b"
+	want = "\"\":\n  type: TXT\n  ttl: 300\n  value: \"a\\\\;b\"\n"
+	if got := records.OctoDNS(apex); got != want {
+		t.Errorf("OctoDNS() = %q, want %q", got, want)
+	}
+}
+
+func TestDNSControl(t *testing.T) {
+	want := `TXT("app.eu", "google-site-verification=abc_DEF-123", TTL(300))`
+	if got := records.DNSControl(record); got != want {
+		t.Errorf("DNSControl() = %q, want %q", got, want)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	got, err := records.JSON(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded records.Record
+	if err := json.Unmarshal([]byte(got), &decoded); err != nil {
+		t.Fatalf("JSON() returned invalid JSON %s: %s", got, err)
+	}
+	if decoded != record {
+		t.Errorf("JSON() round trip = %+v, want %+v", decoded, record)
+	}
+}
@@ -0,0 +1,108 @@
+// Package siteid parses the identifiers used by the Site Verification API
+// to name verified web resources.
+package siteid
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+const (
+	// TypeDomain is the site type of a DNS domain verification.
+	TypeDomain = "INET_DOMAIN"
+	// TypeSite is the site type of a URL-prefix verification.
+	TypeSite = "SITE"
+
+	dnsScheme = "dns://"
+)
+
+var errEmpty = errors.New("id must not be empty")
+
+// Site is a parsed web resource id.
+type Site struct {
+	// Type is either TypeDomain or TypeSite.
+	Type string
+	// Identifier is the bare domain for TypeDomain, or the URL prefix for TypeSite.
+	Identifier string
+}
+
+// ID returns the canonical, decoded web resource id for the site.
+func (s Site) ID() string {
+	if s.Type == TypeDomain {
+		return dnsScheme + s.Identifier
+	}
+	return s.Identifier
+}
+
+// IsDomain reports whether the site is a DNS domain.
+func (s Site) IsDomain() bool {
+	return s.Type == TypeDomain
+}
+
+// Parse accepts a bare domain ("example.com"), a domain id ("dns://example.com"),
+// its URL-encoded form ("dns%3A%2F%2Fexample.com") as returned by the API, or
+// a URL-prefix id ("https://example.com/").
+func Parse(id string) (Site, error) {
+	raw := strings.TrimSpace(id)
+	if raw == "" {
+		return Site{}, errEmpty
+	}
+	if strings.Contains(raw, "%") {
+		decoded, err := url.QueryUnescape(raw)
+		if err != nil {
+			return Site{}, fmt.Errorf("failed to urldecode id %q: %w", id, err)
+		}
+		raw = strings.TrimSpace(decoded)
+	}
+
+	lower := strings.ToLower(raw)
+	switch {
+	case strings.HasPrefix(lower, dnsScheme):
+		return parseDomain(raw[len(dnsScheme):], id)
+	case strings.HasPrefix(lower, "http://"), strings.HasPrefix(lower, "https://"):
+		return parseSite(raw, id)
+	case strings.Contains(raw, "://"):
+		return Site{}, fmt.Errorf("unsupported scheme in id %q", id)
+	default:
+		return parseDomain(raw, id)
+	}
+}
+
+func parseDomain(domain, id string) (Site, error) {
+	if domain == "" {
+		return Site{}, fmt.Errorf("id %q has no domain", id)
+	}
+	if i := strings.IndexFunc(domain, invalidDomainRune); i >= 0 {
+		return Site{}, fmt.Errorf("id %q contains invalid character %q", id, domain[i])
+	}
+	return Site{Type: TypeDomain, Identifier: domain}, nil
+}
+
+func parseSite(raw, id string) (Site, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Site{}, fmt.Errorf("failed to parse id %q: %w", id, err)
+	}
+	if u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return Site{}, fmt.Errorf("id %q is not a valid URL prefix", id)
+	}
+	if strings.IndexFunc(raw, invalidSiteRune) >= 0 {
+		return Site{}, fmt.Errorf("id %q contains invalid characters", id)
+	}
+	return Site{Type: TypeSite, Identifier: raw}, nil
+}
+
+func invalidDomainRune(r rune) bool {
+	switch r {
+	case '/', '\\', '?', '#', '@', '%', ':':
+		return true
+	}
+	return unicode.IsSpace(r) || unicode.IsControl(r)
+}
+
+func invalidSiteRune(r rune) bool {
+	return r == '%' || unicode.IsSpace(r) || unicode.IsControl(r)
+}
@@ -0,0 +1,99 @@
+package siteid_test
+
+import (
+	"net/url"
+	"testing"
+
+	"giautm.dev/googlesiteverification/internal/siteid"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want siteid.Site
+	}{
+		{"example.com", siteid.Site{Type: siteid.TypeDomain, Identifier: "example.com"}},
+		{"dns://example.com", siteid.Site{Type: siteid.TypeDomain, Identifier: "example.com"}},
+		{"DNS://example.com", siteid.Site{Type: siteid.TypeDomain, Identifier: "example.com"}},
+		{"dns%3A%2F%2Fexample.com", siteid.Site{Type: siteid.TypeDomain, Identifier: "example.com"}},
+		{" dns://sub.example.com ", siteid.Site{Type: siteid.TypeDomain, Identifier: "sub.example.com"}},
+		{"https://example.com/", siteid.Site{Type: siteid.TypeSite, Identifier: "https://example.com/"}},
+		{"http://example.com/blog/", siteid.Site{Type: siteid.TypeSite, Identifier: "http://example.com/blog/"}},
+		{"https%3A%2F%2Fexample.com%2F", siteid.Site{Type: siteid.TypeSite, Identifier: "https://example.com/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := siteid.Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %s", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"   ",
+		"dns://",
+		"ftp://example.com",
+		"example.com/path",
+		"user@example.com",
+		"exa mple.com",
+		"dns%ZZexample.com",
+		"dns%253A%252F%252Fexample.com",
+		"https://",
+		"https://example.com/?q=1",
+	} {
+		t.Run(in, func(t *testing.T) {
+			if got, err := siteid.Parse(in); err == nil {
+				t.Errorf("Parse(%q) = %+v, want error", in, got)
+			}
+		})
+	}
+}
+
+func TestSiteID(t *testing.T) {
+	if got := (siteid.Site{Type: siteid.TypeDomain, Identifier: "example.com"}).ID(); got != "dns://example.com" {
+		t.Errorf("ID() = %q, want %q", got, "dns://example.com")
+	}
+	if got := (siteid.Site{Type: siteid.TypeSite, Identifier: "https://example.com/"}).ID(); got != "https://example.com/" {
+		t.Errorf("ID() = %q, want %q", got, "https://example.com/")
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"example.com",
+		"dns://example.com",
+		"dns%3A%2F%2Fexample.com",
+		"https://example.com/",
+		"https%3A%2F%2Fexample.com%2F",
+		"",
+		"%",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		site, err := siteid.Parse(in)
+		if err != nil {
+			return
+		}
+		if site.Identifier == "" {
+			t.Fatalf("Parse(%q) returned an empty identifier", in)
+		}
+		// The canonical id and its encoded form must parse back to the same site.
+		for _, id := range []string{site.ID(), url.QueryEscape(site.ID())} {
+			again, err := siteid.Parse(id)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed on canonical id %q: %s", in, id, err)
+			}
+			if again != site {
+				t.Fatalf("Parse(%q) = %+v, but Parse(%q) = %+v", in, site, id, again)
+			}
+		}
+	})
+}
@@ -3,11 +3,16 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 
-	"giautm.dev/googlesiteverification/internal/provider"
+	"giautm.dev/googlesiteverification/internal/cli"
+	"giautm.dev/googlesiteverification/provider"
 )
 
 // Run "go generate" to format example terraform files and generate the docs for the registry/website
@@ -29,20 +34,93 @@ var (
 	// commit  string = ""
 )
 
+const (
+	// defaultAddress is the address of the provider in the Terraform
+	// registry.
+	defaultAddress = "registry.terraform.io/giautm/googlesiteverification"
+
+	// addressEnvVar overrides defaultAddress, for example with the address
+	// of an OpenTofu registry or a provider mirror.
+	addressEnvVar = "GOOGLE_SITEVERIFICATION_PROVIDER_ADDRESS"
+	// protocolEnvVar sets the protocol version like the -protocol flag, as
+	// Terraform launches the provider without flags.
+	protocolEnvVar = "GOOGLE_SITEVERIFICATION_PROVIDER_PROTOCOL"
+	// pluginProtocolVersionsEnvVar lists the protocol versions supported by
+	// the Terraform CLI launching the provider.
+	pluginProtocolVersionsEnvVar = "PLUGIN_PROTOCOL_VERSIONS"
+)
+
 func main() {
-	var debug bool
+	if cli.IsCommand(os.Args[1:]) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		code := cli.Run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+		stop()
+		os.Exit(code)
+	}
+
+	var (
+		debug    bool
+		address  string
+		protocol string
+	)
 
 	flag.BoolVar(&debug, "debug", false, "set to true to run the provider with support for debuggers like delve")
+	flag.StringVar(&address, "address", envOr(addressEnvVar, defaultAddress), "the full address of the provider, such as registry.opentofu.org/giautm/googlesiteverification")
+	flag.StringVar(&protocol, "protocol", envOr(protocolEnvVar, "auto"), `the protocol version to serve: 5, 6, or "auto" to pick the latest supported by Terraform`)
 	flag.Parse()
 
+	protocolVersion, err := negotiateProtocol(protocol, os.Getenv(pluginProtocolVersionsEnvVar))
+	if err != nil {
+		log.Fatal(err.Error())
+	}
 	opts := providerserver.ServeOpts{
-		// TODO: Update this string with the published name of your provider.
-		Address: "registry.terraform.io/giautm/googlesiteverification",
-		Debug:   debug,
+		Address:         address,
+		Debug:           debug,
+		ProtocolVersion: protocolVersion,
 	}
 
-	err := providerserver.Serve(context.Background(), provider.New(version), opts)
+	factory := provider.New(version)
+	if protocolVersion == 5 {
+		factory = provider.NewProtocol5(version)
+	}
+	err = providerserver.Serve(context.Background(), factory, opts)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 }
+
+// negotiateProtocol returns the protocol version to serve. With "auto", it is
+// version 6 unless the comma separated versions supported by Terraform only
+// include version 5, as with Terraform 0.12 to 0.14.
+func negotiateProtocol(protocol, supported string) (int, error) {
+	switch protocol {
+	case "5":
+		return 5, nil
+	case "6":
+		return 6, nil
+	case "auto", "":
+		versions := strings.Split(supported, ",")
+		if contains(versions, "5") && !contains(versions, "6") {
+			return 5, nil
+		}
+		return 6, nil
+	default:
+		return 0, fmt.Errorf("unsupported protocol %q, expected 5, 6 or auto", protocol)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
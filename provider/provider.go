@@ -0,0 +1,54 @@
+// Package provider exposes the googlesiteverification Terraform provider, so
+// that it can be served from other binaries, for example combined with other
+// providers by tf6muxserver:
+//
+//	server, err := tf6muxserver.NewMuxServer(ctx,
+//		providerserver.NewProtocol6(provider.New(version)()),
+//		providerserver.NewProtocol6(platform.New(version)()),
+//	)
+//
+// Muxed providers must declare the same provider schema, which is the
+// schema of the provider returned by New.
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+
+	internal "giautm.dev/googlesiteverification/internal/provider"
+)
+
+// TypeName is the name of the provider, which prefixes its resources and
+// data sources.
+const TypeName = "googlesiteverification"
+
+// New returns the factory of the provider with the given version.
+func New(version string) func() provider.Provider {
+	return internal.New(version)
+}
+
+// NewProtocol5 returns the factory of the provider with the given version,
+// for providerserver.NewProtocol5 and tf5muxserver. Protocol version 5 does
+// not support nested attributes, so googlesiteverification_domain_set and
+// the googlesiteverification_domains data source fail with an error asking
+// for protocol version 6.
+func NewProtocol5(version string) func() provider.Provider {
+	return internal.NewProtocol5(version)
+}
+
+// Resources returns the resources of the provider, such as
+// googlesiteverification_domain. They must be configured by the provider
+// returned by New.
+func Resources() []func() resource.Resource {
+	return internal.New("")().Resources(context.Background())
+}
+
+// DataSources returns the data sources of the provider, such as
+// googlesiteverification_domain. They must be configured by the provider
+// returned by New.
+func DataSources() []func() datasource.DataSource {
+	return internal.New("")().DataSources(context.Background())
+}
@@ -0,0 +1,244 @@
+package provider_test
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	tfprovider "github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-mux/tf6muxserver"
+
+	"giautm.dev/googlesiteverification/provider"
+)
+
+// platformProvider is an in-house provider muxed with googlesiteverification.
+// It declares the same provider schema, as tf6muxserver requires.
+type platformProvider struct {
+	schema func(context.Context) (tfsdk.Schema, diag.Diagnostics)
+}
+
+func (p *platformProvider) Metadata(ctx context.Context, req tfprovider.MetadataRequest, resp *tfprovider.MetadataResponse) {
+	resp.TypeName = "platform"
+}
+
+func (p *platformProvider) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return p.schema(ctx)
+}
+
+func (p *platformProvider) Configure(ctx context.Context, req tfprovider.ConfigureRequest, resp *tfprovider.ConfigureResponse) {
+}
+
+func (p *platformProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return nil
+}
+
+func (p *platformProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		func() datasource.DataSource { return &platformDataSource{} },
+	}
+}
+
+type platformDataSource struct{}
+
+func (d *platformDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = "platform_info"
+}
+
+func (d *platformDataSource) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {Type: types.StringType, Computed: true},
+		},
+	}, nil
+}
+
+func (d *platformDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+}
+
+func TestMuxServer(t *testing.T) {
+	ctx := context.Background()
+	platform := &platformProvider{schema: provider.New("test")().GetSchema}
+	mux, err := tf6muxserver.NewMuxServer(ctx,
+		providerserver.NewProtocol6(provider.New("test")()),
+		providerserver.NewProtocol6(platform),
+	)
+	if err != nil {
+		t.Fatalf("NewMuxServer() returned error: %s", err)
+	}
+
+	resp, err := mux.ProviderServer().GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Diagnostics) > 0 {
+		t.Fatalf("GetProviderSchema() diagnostics = %v", resp.Diagnostics)
+	}
+	if got, want := keys(resp.ResourceSchemas), "googlesiteverification_domain,googlesiteverification_domain_set"; got != want {
+		t.Errorf("resources = %s, want %s", got, want)
+	}
+	if got, want := keys(resp.DataSourceSchemas), "googlesiteverification_domain,googlesiteverification_domains,platform_info"; got != want {
+		t.Errorf("data sources = %s, want %s", got, want)
+	}
+	if resp.Provider == nil || len(resp.Provider.Block.Attributes) == 0 {
+		t.Error("provider schema has no attributes")
+	}
+}
+
+func TestMuxServerSchemaMismatch(t *testing.T) {
+	ctx := context.Background()
+	platform := &platformProvider{schema: func(context.Context) (tfsdk.Schema, diag.Diagnostics) {
+		return tfsdk.Schema{}, nil
+	}}
+	_, err := tf6muxserver.NewMuxServer(ctx,
+		providerserver.NewProtocol6(provider.New("test")()),
+		providerserver.NewProtocol6(platform),
+	)
+	if err == nil || !strings.Contains(err.Error(), "different provider schema") {
+		t.Errorf("NewMuxServer() returned error %v, want a provider schema mismatch", err)
+	}
+}
+
+func TestResourcesAndDataSources(t *testing.T) {
+	ctx := context.Background()
+	var resources, dataSources []string
+	for _, f := range provider.Resources() {
+		resp := &resource.MetadataResponse{}
+		f().Metadata(ctx, resource.MetadataRequest{ProviderTypeName: provider.TypeName}, resp)
+		resources = append(resources, resp.TypeName)
+	}
+	for _, f := range provider.DataSources() {
+		resp := &datasource.MetadataResponse{}
+		f().Metadata(ctx, datasource.MetadataRequest{ProviderTypeName: provider.TypeName}, resp)
+		dataSources = append(dataSources, resp.TypeName)
+	}
+	sort.Strings(resources)
+	sort.Strings(dataSources)
+	if got, want := strings.Join(resources, ","), "googlesiteverification_domain,googlesiteverification_domain_set"; got != want {
+		t.Errorf("Resources() = %s, want %s", got, want)
+	}
+	if got, want := strings.Join(dataSources, ","), "googlesiteverification_domain,googlesiteverification_domains"; got != want {
+		t.Errorf("DataSources() = %s, want %s", got, want)
+	}
+}
+
+func TestProtocol5(t *testing.T) {
+	ctx := context.Background()
+	for _, tt := range []struct {
+		name            string
+		factory         func() tfprovider.Provider
+		wantResources   string
+		wantDataSources string
+		wantErr         bool
+	}{
+		{
+			name:            "protocol 5",
+			factory:         provider.NewProtocol5("test"),
+			wantResources:   "googlesiteverification_domain,googlesiteverification_domain_set",
+			wantDataSources: "googlesiteverification_domain,googlesiteverification_domains",
+		},
+		{
+			name:    "nested attributes",
+			factory: provider.New("test"),
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := providerserver.NewProtocol5(tt.factory())().GetProviderSchema(ctx, &tfprotov5.GetProviderSchemaRequest{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotErr := len(resp.Diagnostics) > 0; gotErr != tt.wantErr {
+				t.Fatalf("GetProviderSchema() diagnostics = %v, want errors %t", resp.Diagnostics, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := keys5(resp.ResourceSchemas); got != tt.wantResources {
+				t.Errorf("resources = %s, want %s", got, tt.wantResources)
+			}
+			if got := keys5(resp.DataSourceSchemas); got != tt.wantDataSources {
+				t.Errorf("data sources = %s, want %s", got, tt.wantDataSources)
+			}
+		})
+	}
+}
+
+func TestProtocol5Unsupported(t *testing.T) {
+	ctx := context.Background()
+	server := providerserver.NewProtocol5(provider.NewProtocol5("test")())()
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov5.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := func(schema *tfprotov5.Schema) *tfprotov5.DynamicValue {
+		t.Helper()
+		typ := schema.ValueType()
+		v, err := tfprotov5.NewDynamicValue(typ, tftypes.NewValue(typ, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &v
+	}
+	// The null configs are also missing required attributes, which adds
+	// errors of its own.
+	wantErr := func(t *testing.T, diags []*tfprotov5.Diagnostic) {
+		t.Helper()
+		for _, d := range diags {
+			if d.Severity == tfprotov5.DiagnosticSeverityError && strings.Contains(d.Detail, "protocol version 6") {
+				return
+			}
+		}
+		t.Errorf("diagnostics = %v, want an error asking for protocol version 6", diags)
+	}
+
+	t.Run("resource", func(t *testing.T) {
+		name := "googlesiteverification_domain_set"
+		resp, err := server.ValidateResourceTypeConfig(ctx, &tfprotov5.ValidateResourceTypeConfigRequest{
+			TypeName: name,
+			Config:   config(schemas.ResourceSchemas[name]),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantErr(t, resp.Diagnostics)
+	})
+	t.Run("data source", func(t *testing.T) {
+		name := "googlesiteverification_domains"
+		resp, err := server.ValidateDataSourceConfig(ctx, &tfprotov5.ValidateDataSourceConfigRequest{
+			TypeName: name,
+			Config:   config(schemas.DataSourceSchemas[name]),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantErr(t, resp.Diagnostics)
+	})
+}
+
+func keys(m map[string]*tfprotov6.Schema) string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func keys5(m map[string]*tfprotov5.Schema) string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,85 @@
+package verifier
+
+import (
+	"context"
+
+	"google.golang.org/api/siteverification/v1"
+)
+
+// The verification method and site type of the domains handled by a
+// Verifier.
+const (
+	MethodDNSTXT = "DNS_TXT"
+	TypeDomain   = "INET_DOMAIN"
+)
+
+// API is the part of the Site Verification API used by a Verifier, so that
+// tests can replace it.
+type API interface {
+	// GetToken returns the DNS TXT verification token for domain.
+	GetToken(ctx context.Context, domain string) (string, error)
+	// Insert verifies domain with its DNS TXT token.
+	Insert(ctx context.Context, domain string) (*siteverification.SiteVerificationWebResourceResource, error)
+	Get(ctx context.Context, id string) (*siteverification.SiteVerificationWebResourceResource, error)
+	// List returns the verifications owned by the caller.
+	List(ctx context.Context) ([]*siteverification.SiteVerificationWebResourceResource, error)
+	Update(ctx context.Context, id string, res *siteverification.SiteVerificationWebResourceResource) (*siteverification.SiteVerificationWebResourceResource, error)
+	// Delete unverifies the verification with the given id.
+	Delete(ctx context.Context, id string) error
+}
+
+// NewAPI returns the API of srv.
+func NewAPI(srv *siteverification.Service) API {
+	return &serviceAPI{srv: srv}
+}
+
+type serviceAPI struct {
+	srv *siteverification.Service
+}
+
+func (a *serviceAPI) GetToken(ctx context.Context, domain string) (string, error) {
+	result, err := a.srv.WebResource.
+		GetToken(&siteverification.SiteVerificationWebResourceGettokenRequest{
+			Site: &siteverification.SiteVerificationWebResourceGettokenRequestSite{
+				Identifier: domain,
+				Type:       TypeDomain,
+			},
+			VerificationMethod: MethodDNSTXT,
+		}).
+		Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	return result.Token, nil
+}
+
+func (a *serviceAPI) Insert(ctx context.Context, domain string) (*siteverification.SiteVerificationWebResourceResource, error) {
+	return a.srv.WebResource.
+		Insert(MethodDNSTXT, &siteverification.SiteVerificationWebResourceResource{
+			Site: &siteverification.SiteVerificationWebResourceResourceSite{
+				Identifier: domain,
+				Type:       TypeDomain,
+			},
+		}).
+		Context(ctx).Do()
+}
+
+func (a *serviceAPI) Get(ctx context.Context, id string) (*siteverification.SiteVerificationWebResourceResource, error) {
+	return a.srv.WebResource.Get(id).Context(ctx).Do()
+}
+
+func (a *serviceAPI) List(ctx context.Context) ([]*siteverification.SiteVerificationWebResourceResource, error) {
+	result, err := a.srv.WebResource.List().Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+func (a *serviceAPI) Update(ctx context.Context, id string, res *siteverification.SiteVerificationWebResourceResource) (*siteverification.SiteVerificationWebResourceResource, error) {
+	return a.srv.WebResource.Update(id, res).Context(ctx).Do()
+}
+
+func (a *serviceAPI) Delete(ctx context.Context, id string) error {
+	return a.srv.WebResource.Delete(id).Context(ctx).Do()
+}
@@ -0,0 +1,289 @@
+// Package verifier verifies the ownership of domains with DNS TXT records
+// through the Google Site Verification API.
+//
+// It is the logic of the googlesiteverification Terraform provider, for use
+// from Go programs:
+//
+//	srv, err := siteverification.NewService(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	v := verifier.New(verifier.NewAPI(srv), verifier.WithPublisher(publisher))
+//	id, err := v.Verify(ctx, "example.com")
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"giautm.dev/googlesiteverification/internal/apierror"
+	"giautm.dev/googlesiteverification/internal/domainname"
+	"giautm.dev/googlesiteverification/internal/siteid"
+)
+
+// DefaultRetryInterval is how long to wait before retrying while a token is
+// not yet visible in DNS, or still visible when unverifying.
+const DefaultRetryInterval = 60 * time.Second
+
+// The classes of API errors, matched with errors.Is on the errors returned
+// by a Verifier.
+var (
+	ErrTokenNotFound     = apierror.ErrTokenNotFound
+	ErrTokenStillPresent = apierror.ErrTokenStillPresent
+	ErrNotOwner          = apierror.ErrNotOwner
+	ErrNotFound          = apierror.ErrNotFound
+	ErrQuota             = apierror.ErrQuota
+	ErrTransient         = apierror.ErrTransient
+)
+
+// Publisher publishes the DNS TXT record of a verification token, for
+// example through the API of a DNS provider.
+type Publisher interface {
+	// Publish adds a TXT record with token to domain.
+	Publish(ctx context.Context, domain, token string) error
+	// Unpublish removes the TXT record with token from domain.
+	Unpublish(ctx context.Context, domain, token string) error
+}
+
+// Verifier verifies and unverifies domains. It is safe for concurrent use.
+type Verifier struct {
+	api       API
+	publisher Publisher
+	limiter   *rate.Limiter
+	interval  time.Duration
+	onRetry   func(ctx context.Context, domain string, err error)
+}
+
+// Option configures a Verifier.
+type Option func(*Verifier)
+
+// WithPublisher publishes the token of a domain before verifying it, and
+// removes it before unverifying it. Without a publisher the records must be
+// managed by the caller.
+func WithPublisher(p Publisher) Option {
+	return func(v *Verifier) { v.publisher = p }
+}
+
+// WithRateLimiter waits for limiter before every call that may be retried.
+func WithRateLimiter(limiter *rate.Limiter) Option {
+	return func(v *Verifier) { v.limiter = limiter }
+}
+
+// WithRetryInterval replaces DefaultRetryInterval.
+func WithRetryInterval(d time.Duration) Option {
+	return func(v *Verifier) { v.interval = d }
+}
+
+// WithRetryHook calls fn with the error of every attempt that is retried.
+func WithRetryHook(fn func(ctx context.Context, domain string, err error)) Option {
+	return func(v *Verifier) { v.onRetry = fn }
+}
+
+// New returns a Verifier using api.
+func New(api API, opts ...Option) *Verifier {
+	v := &Verifier{api: api, interval: DefaultRetryInterval}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Token returns the DNS TXT verification token for domain.
+func (v *Verifier) Token(ctx context.Context, domain string) (string, error) {
+	domain, err := domainname.Normalize(domain)
+	if err != nil {
+		return "", err
+	}
+	token, err := v.api.GetToken(ctx, domain)
+	return token, apierror.Wrap(err)
+}
+
+// Verify verifies domain, publishing its token first if the Verifier has a
+// publisher. It retries until ctx ends while the token is not yet visible in
+// DNS, and returns the id of the verification, such as dns://example.com.
+func (v *Verifier) Verify(ctx context.Context, domain string) (string, error) {
+	domain, err := domainname.Normalize(domain)
+	if err != nil {
+		return "", err
+	}
+	if v.publisher != nil {
+		token, err := v.Token(ctx, domain)
+		if err != nil {
+			return "", err
+		}
+		if err := v.publisher.Publish(ctx, domain, token); err != nil {
+			return "", fmt.Errorf("publishing token: %w", err)
+		}
+	}
+
+	var id string
+	err = v.retry(ctx, domain, apierror.TokenNotFound, func() error {
+		result, err := v.api.Insert(ctx, domain)
+		if err != nil {
+			return err
+		}
+		site, err := siteid.Parse(result.Id)
+		if err != nil {
+			return err
+		}
+		id = site.ID()
+		return nil
+	})
+	return id, err
+}
+
+// Unverify deletes the verification with the given id, removing its token
+// first if the Verifier has a publisher. It retries until ctx ends while the
+// token is still visible in DNS.
+func (v *Verifier) Unverify(ctx context.Context, id string) error {
+	site, err := siteid.Parse(id)
+	if err != nil {
+		return err
+	}
+	if v.publisher != nil && site.IsDomain() {
+		token, err := v.Token(ctx, site.Identifier)
+		if err != nil {
+			return err
+		}
+		if err := v.publisher.Unpublish(ctx, site.Identifier, token); err != nil {
+			return fmt.Errorf("unpublishing token: %w", err)
+		}
+	}
+
+	return v.retry(ctx, site.Identifier, apierror.TokenStillPresent, func() error {
+		return v.api.Delete(ctx, site.ID())
+	})
+}
+
+// Owners returns the owners of the verification with the given id.
+func (v *Verifier) Owners(ctx context.Context, id string) ([]string, error) {
+	site, err := siteid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := v.api.Get(ctx, site.ID())
+	if err != nil {
+		return nil, apierror.Wrap(err)
+	}
+	return res.Owners, nil
+}
+
+// AddOwner adds email to the owners of the verification with the given id,
+// and returns the owners.
+func (v *Verifier) AddOwner(ctx context.Context, id, email string) ([]string, error) {
+	return v.updateOwners(ctx, id, func(owners []string) []string {
+		for _, owner := range owners {
+			if owner == email {
+				return owners
+			}
+		}
+		return append(owners, email)
+	})
+}
+
+// RemoveOwner removes email from the owners of the verification with the
+// given id, and returns the owners.
+func (v *Verifier) RemoveOwner(ctx context.Context, id, email string) ([]string, error) {
+	return v.updateOwners(ctx, id, func(owners []string) []string {
+		kept := owners[:0]
+		for _, owner := range owners {
+			if owner != email {
+				kept = append(kept, owner)
+			}
+		}
+		return kept
+	})
+}
+
+func (v *Verifier) updateOwners(ctx context.Context, id string, update func([]string) []string) ([]string, error) {
+	site, err := siteid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	res, err := v.api.Get(ctx, site.ID())
+	if err != nil {
+		return nil, apierror.Wrap(err)
+	}
+	res.Owners = update(res.Owners)
+	res, err = v.api.Update(ctx, site.ID(), res)
+	if err != nil {
+		return nil, apierror.Wrap(err)
+	}
+	return res.Owners, nil
+}
+
+// retry calls fn until it does not fail with an API error of the given
+// kind, waiting for the interval in between.
+func (v *Verifier) retry(ctx context.Context, domain string, kind apierror.Kind, fn func() error) error {
+	var last error
+	for {
+		if err := v.wait(ctx); err != nil {
+			return retried(err, last)
+		}
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if apierror.Classify(err) == kind {
+			last = apierror.Wrap(err)
+			if v.onRetry != nil {
+				v.onRetry(ctx, domain, last)
+			}
+			if err := sleep(ctx, v.interval); err != nil {
+				return retried(err, last)
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			return retried(err, last)
+		}
+		return apierror.Wrap(err)
+	}
+}
+
+func (v *Verifier) wait(ctx context.Context) error {
+	if v.limiter == nil {
+		return nil
+	}
+	return v.limiter.Wait(ctx)
+}
+
+// retried returns err, which ended a retry loop, along with the last API
+// error that was retried, if any.
+func retried(err, last error) error {
+	if last == nil {
+		return err
+	}
+	return &retryError{err: err, last: last}
+}
+
+// retryError is returned when the context ends while retrying. It matches
+// the context error with errors.Is, and unwraps to the last API error so that
+// it can still be classified.
+type retryError struct {
+	err  error
+	last error
+}
+
+func (e *retryError) Error() string {
+	return fmt.Sprintf("%s, last error: %s", e.err, e.last)
+}
+
+func (e *retryError) Unwrap() error { return e.last }
+
+func (e *retryError) Is(target error) bool { return errors.Is(e.err, target) }
+
+func sleep(ctx context.Context, interval time.Duration) error {
+	t := time.NewTimer(interval)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,186 @@
+package verifier_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/siteverification/v1"
+
+	"giautm.dev/googlesiteverification/internal/fake"
+	"giautm.dev/googlesiteverification/internal/siteid"
+	"giautm.dev/googlesiteverification/verifier"
+)
+
+// testPublisher is a DNS zone in memory, checked by the fake API.
+type testPublisher struct {
+	mu      sync.Mutex
+	records map[string]string
+	err     error
+}
+
+func (p *testPublisher) Publish(ctx context.Context, domain, token string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.records[domain] = token
+	return nil
+}
+
+func (p *testPublisher) Unpublish(ctx context.Context, domain, token string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.records[domain] == token {
+		delete(p.records, domain)
+	}
+	return nil
+}
+
+func (p *testPublisher) published(ctx context.Context, site siteid.Site, token string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.records[site.Identifier] == token, nil
+}
+
+func testAPI(t *testing.T, opts ...fake.Option) (*fake.Server, verifier.API) {
+	t.Helper()
+
+	s := fake.New(opts...)
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	svc, err := siteverification.NewService(context.Background(),
+		option.WithoutAuthentication(),
+		option.WithEndpoint(srv.URL+"/siteVerification/v1/"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, verifier.NewAPI(svc)
+}
+
+func TestVerifyWithPublisher(t *testing.T) {
+	ctx := context.Background()
+	p := &testPublisher{records: map[string]string{}}
+	s, api := testAPI(t,
+		fake.WithPublishedCheck(p.published),
+		fake.WithRemovedCheck(fake.Not(p.published)),
+	)
+	v := verifier.New(api, verifier.WithPublisher(p), verifier.WithRetryInterval(time.Millisecond))
+
+	token, err := v.Token(ctx, "Example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != s.Token("example.com") {
+		t.Errorf("Token() = %q, want %q", token, s.Token("example.com"))
+	}
+
+	id, err := v.Verify(ctx, "Example.com.")
+	if err != nil {
+		t.Fatalf("Verify() returned error: %s", err)
+	}
+	if id != "dns://example.com" || !s.Verified("example.com") || p.records["example.com"] != token {
+		t.Errorf("Verify() = %q, verified %t, records %v", id, s.Verified("example.com"), p.records)
+	}
+
+	if err := v.Unverify(ctx, id); err != nil {
+		t.Fatalf("Unverify() returned error: %s", err)
+	}
+	if s.Verified("example.com") || len(p.records) != 0 {
+		t.Errorf("Unverify() left verified %t, records %v", s.Verified("example.com"), p.records)
+	}
+
+	p.err = errors.New("zone is read-only")
+	if _, err := v.Verify(ctx, "example.com"); !errors.Is(err, p.err) {
+		t.Errorf("Verify() returned error %v, want the publisher error", err)
+	}
+}
+
+func TestVerifyRetries(t *testing.T) {
+	ctx := context.Background()
+	var retries []string
+	s, api := testAPI(t, fake.WithPublishedCheck(fake.After(2)), fake.WithRemovedCheck(fake.After(1)))
+	v := verifier.New(api,
+		verifier.WithRetryInterval(time.Millisecond),
+		verifier.WithRetryHook(func(ctx context.Context, domain string, err error) {
+			retries = append(retries, domain)
+			if !errors.Is(err, verifier.ErrTokenNotFound) && !errors.Is(err, verifier.ErrTokenStillPresent) {
+				t.Errorf("retried error %v, want a token error", err)
+			}
+		}),
+	)
+
+	id, err := v.Verify(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Verify() returned error: %s", err)
+	}
+	if err := v.Unverify(ctx, id); err != nil {
+		t.Fatalf("Unverify() returned error: %s", err)
+	}
+	if s.Calls(fake.MethodInsert) != 3 || s.Calls(fake.MethodDelete) != 2 || len(retries) != 3 {
+		t.Errorf("inserts = %d, deletes = %d, retries = %v", s.Calls(fake.MethodInsert), s.Calls(fake.MethodDelete), retries)
+	}
+}
+
+func TestVerifyTimeout(t *testing.T) {
+	_, api := testAPI(t, fake.WithPublishedCheck(fake.Never()))
+	v := verifier.New(api, verifier.WithRetryInterval(time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := v.Verify(ctx, "example.com")
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, verifier.ErrTokenNotFound) {
+		t.Errorf("Verify() returned error %v, want a deadline with the last token error", err)
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	ctx := context.Background()
+	s, api := testAPI(t)
+	v := verifier.New(api, verifier.WithRetryInterval(time.Millisecond))
+
+	if _, err := v.Verify(ctx, "exa mple.com"); err == nil {
+		t.Error("Verify() returned no error for an invalid domain")
+	}
+	s.Inject(fake.MethodDelete, http.StatusForbidden, "You are not an owner of this site.", 1)
+	if err := v.Unverify(ctx, "dns://example.com"); !errors.Is(err, verifier.ErrNotOwner) {
+		t.Errorf("Unverify() returned error %v, want ErrNotOwner", err)
+	}
+	if _, err := v.Owners(ctx, "dns://example.com"); !errors.Is(err, verifier.ErrNotFound) {
+		t.Errorf("Owners() returned error %v, want ErrNotFound", err)
+	}
+}
+
+func TestOwners(t *testing.T) {
+	ctx := context.Background()
+	s, api := testAPI(t)
+	s.Put("example.com", "a@example.com")
+	v := verifier.New(api)
+
+	for _, tt := range []struct {
+		name string
+		op   func() ([]string, error)
+		want []string
+	}{
+		{name: "owners", op: func() ([]string, error) { return v.Owners(ctx, "example.com") }, want: []string{"a@example.com"}},
+		{name: "add", op: func() ([]string, error) { return v.AddOwner(ctx, "example.com", "b@example.com") }, want: []string{"a@example.com", "b@example.com"}},
+		{name: "add again", op: func() ([]string, error) { return v.AddOwner(ctx, "dns://example.com", "b@example.com") }, want: []string{"a@example.com", "b@example.com"}},
+		{name: "remove", op: func() ([]string, error) { return v.RemoveOwner(ctx, "example.com", "a@example.com") }, want: []string{"b@example.com"}},
+	} {
+		got, err := tt.op()
+		if err != nil {
+			t.Fatalf("%s returned error: %s", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}